@@ -0,0 +1,68 @@
+package main
+
+import (
+	"ProjectMessageService/internal/repository"
+	"ProjectMessageService/util"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+)
+
+func runCreateAdmin(args []string) error {
+	flags := flag.NewFlagSet("create-admin", flag.ExitOnError)
+	username := flags.String("username", "", "имя пользователя (обязательно)")
+	password := flags.String("password", os.Getenv("ADMIN_PASSWORD"), "пароль нового пользователя, по умолчанию из ADMIN_PASSWORD")
+	fullName := flags.String("full-name", "Administrator", "полное имя нового пользователя")
+	email := flags.String("email", "", "email нового пользователя")
+	_ = flags.Parse(args)
+
+	if *username == "" {
+		return errors.New("-username is required")
+	}
+
+	env := setup()
+	defer env.close()
+	ctx := context.Background()
+
+	// Существующий пользователь получает роль admin, пароль не меняется
+	err := env.repo.UpdateUserRole(ctx, *username, util.AdminRole)
+	if err == nil {
+		fmt.Printf("Пользователю %s выдана роль %s\n", *username, util.AdminRole)
+		return nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+
+	if len(*password) < 6 || *email == "" {
+		return errors.New("-password (at least 6 characters) and -email are required for a new user")
+	}
+	if strings.HasPrefix(*username, util.CertOwnerPrefix) {
+		return fmt.Errorf("-username must not start with %s", util.CertOwnerPrefix)
+	}
+	hashedPassword, err := util.HashPassword(*password)
+	if err != nil {
+		return err
+	}
+
+	_, err = env.repo.CreateUser(ctx, repository.CreateUserParams{
+		Username:       *username,
+		HashedPassword: hashedPassword,
+		FullName:       *fullName,
+		Email:          *email,
+	})
+	if err != nil {
+		return err
+	}
+	if err = env.repo.UpdateUserRole(ctx, *username, util.AdminRole); err != nil {
+		return err
+	}
+
+	fmt.Printf("Создан администратор %s\n", *username)
+	return nil
+}
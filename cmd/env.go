@@ -0,0 +1,109 @@
+package main
+
+import (
+	"ProjectMessageService/config"
+	"ProjectMessageService/internal/metrics"
+	"ProjectMessageService/internal/repository"
+	"ProjectMessageService/internal/service"
+	"ProjectMessageService/internal/tracing"
+	"context"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/segmentio/kafka-go"
+)
+
+// environment - общие зависимости подкоманд.
+type environment struct {
+	app      *config.Application
+	cfg      config.Config
+	settings *config.Store
+	db       *pgxpool.Pool
+	repo     *repository.Repository
+
+	shutdownTracing func(context.Context) error
+}
+
+// setup загружает конфигурацию, настраивает логгер и трассировку и подключается к базе данных.
+func setup() *environment {
+	// Настраиваем логгер
+	app := config.SetupApplication()
+	cfg := config.LoadConfig(app)
+	app.ApplyLogConfig(cfg)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.TracingExporter, cfg.OTLPEndpoint)
+	if err != nil {
+		app.Log.Fatalf("Не удалось настроить трассировку: %v", err)
+	}
+
+	db, err := repository.NewPostgresDB(cfg, 5)
+	if err != nil {
+		app.Log.Fatalf("Не удалось подключиться к базе данных: %v", err)
+	}
+
+	return &environment{
+		app:             app,
+		cfg:             cfg,
+		settings:        config.NewStore(cfg, app),
+		db:              db,
+		repo:            repository.NewRepository(db, app),
+		shutdownTracing: shutdownTracing,
+	}
+}
+
+func (e *environment) migrate() {
+	if err := repository.RunMigrations(e.db, config.MessageTypes()); err != nil {
+		e.app.Log.Fatalf("Не удалось выполнить миграцию: %v", err)
+	}
+}
+
+// newMessageService создает писателя Kafka и сервис сообщений. Диспетчер вебхуков не запущен.
+func (e *environment) newMessageService() (*service.MessageService, *kafka.Writer, *service.WebhookDispatcher) {
+	kafkaWriter := service.NewKafkaWriter(e.cfg)
+
+	metrics.RegisterPool(e.db)
+	metrics.RegisterKafkaWriter(kafkaWriter)
+
+	webhooks := service.NewWebhookDispatcher(e.repo, e.app)
+	schemas := service.NewSchemaRegistry(e.repo, e.app)
+
+	processors := service.NewProcessorRegistry(schemas, e.app)
+
+	ttls, err := e.cfg.TopicTTLs()
+	if err != nil {
+		e.app.Log.Fatalf("Не удалось разобрать MESSAGE_TTL: %v", err)
+	}
+	policies, err := e.cfg.RetentionPolicies()
+	if err != nil {
+		e.app.Log.Fatalf("Не удалось разобрать RETENTION: %v", err)
+	}
+	priorities, err := e.cfg.PriorityWeights()
+	if err != nil {
+		e.app.Log.Fatalf("Не удалось разобрать PRIORITIES: %v", err)
+	}
+	workers, err := e.cfg.ConsumerWorkers()
+	if err != nil {
+		e.app.Log.Fatalf("Не удалось разобрать CONSUMER_WORKERS: %v", err)
+	}
+
+	messageService := service.NewMessageService(e.repo, kafkaWriter, webhooks, schemas, processors, e.app)
+	messageService.SetTopicTTLs(ttls)
+	messageService.SetRetentionPolicies(policies)
+	messageService.SetPriorityWeights(priorities)
+	messageService.SetConsumerWorkers(workers)
+
+	// Конвейеры из PROCESSORS настраиваются после шагов route и store, добавленных сервисом;
+	// конвейеры, зарегистрированные в коде, добавляются через processors.Register
+	pipelines, err := e.cfg.ProcessorPipelines()
+	if err == nil {
+		err = processors.Configure(pipelines)
+	}
+	if err != nil {
+		e.app.Log.Fatalf("Не удалось настроить конвейеры обработки: %v", err)
+	}
+	return messageService, kafkaWriter, webhooks
+}
+
+func (e *environment) close() {
+	e.db.Close()
+	_ = e.shutdownTracing(context.Background())
+}
@@ -1,48 +1,51 @@
 package main
 
 import (
-	"ProjectMessageService/config"
-	"ProjectMessageService/internal/handler"
-	"ProjectMessageService/internal/repository"
-	"ProjectMessageService/internal/service"
-	`context`
-
-	"github.com/gin-gonic/gin"
+	"fmt"
+	"os"
 )
 
-func main() {
+// command - подкоманда CLI. run получает аргументы после имени команды.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
 
-	// Настраиваем логгер
-	app := config.SetupApplication()
-	cfg := config.LoadConfig(app)
+var commands = []command{
+	{"serve", "HTTP API (с флагом -consumers также консьюмеры Kafka)", runServe},
+	{"consume", "консьюмеры Kafka без HTTP API", runConsume},
+	{"migrate", "миграции базы данных", runMigrate},
+	{"create-admin", "создание администратора или выдача роли admin", runCreateAdmin},
+	{"topics", "список топиков (list) и создание топика (create)", runTopics},
+	{"replay", "повторная обработка сохраненных сообщений через Kafka", runReplay},
+	{"reset-offsets", "сброс смещений группы консьюмера топика", runResetOffsets},
+}
 
-	db, err := repository.NewPostgresDB(cfg, 5)
-	if err != nil {
-		app.Log.Fatalf("Не удалось подключиться к базе данных: %v", err)
+func main() {
+	// Без подкоманды сервис работает как раньше: HTTP API и консьюмеры в одном процессе
+	args := os.Args[1:]
+	if len(args) == 0 {
+		args = []string{"serve", "-consumers"}
 	}
 
-	if err = repository.RunMigrations(db, config.MessageTypes); err != nil {
-		app.Log.Fatalf("Не удалось выполнить миграцию: %v", err)
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			if err := cmd.run(args[1:]); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", cmd.name, err)
+				os.Exit(1)
+			}
+			return
+		}
 	}
 
-	repo := repository.NewRepository(db, app)
-	kafkaWriter := service.NewKafkaWriter(cfg)
-
-	messageService := service.NewMessageService(repo, kafkaWriter, app)
-	newHandler := handler.NewHandler(cfg, messageService, repo, app)
-
-	go messageService.ConsumeMessages(context.Background(), cfg, config.MessageTypes)
-
-	gin.SetMode(gin.ReleaseMode)
-	r := gin.Default()
-	r.POST("/users", newHandler.CreateUser)
-	r.POST("/users/login", newHandler.LoginUser)
-	r.POST("/token/renew_access", newHandler.RenewAccessToken)
+	usage()
+	os.Exit(2)
+}
 
-	authRoutes := r.Group("/").Use(handler.AuthMiddleware(newHandler.TokenMaker))
-	authRoutes.POST("/messages", newHandler.CreateMessage)
-	authRoutes.GET("/stats", newHandler.GetStats)
-	if err = r.Run(":8080"); err != nil {
-		app.Log.Fatalf("Не удалось запустить сервер: %v", err)
+func usage() {
+	fmt.Fprintf(os.Stderr, "Использование: %s <команда> [флаги]\n\nКоманды:\n", os.Args[0])
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-14s %s\n", cmd.name, cmd.summary)
 	}
 }
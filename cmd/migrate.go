@@ -0,0 +1,17 @@
+package main
+
+import (
+	"flag"
+)
+
+func runMigrate(args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	_ = flags.Parse(args)
+
+	env := setup()
+	defer env.close()
+
+	env.migrate()
+	env.app.Log.Infof("Миграции выполнены")
+	return nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"ProjectMessageService/config"
+	"ProjectMessageService/internal/repository"
+	"ProjectMessageService/internal/service"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+)
+
+func runReplay(args []string) error {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	topic := flags.String("topic", "", "тип сообщений (обязательно)")
+	from := flags.String("from", "", "только сообщения, созданные не раньше (RFC3339)")
+	to := flags.String("to", "", "только сообщения, созданные раньше (RFC3339)")
+	afterID := flags.Int("after-id", 0, "только сообщения с id больше")
+	unprocessed := flags.Bool("unprocessed", false, "только необработанные сообщения")
+	limit := flags.Int("limit", 0, "не больше N сообщений, 0 - без ограничения")
+	dryRun := flags.Bool("dry-run", false, "только посчитать подходящие сообщения")
+	_ = flags.Parse(args)
+
+	filter := repository.ListMessagesParams{Topic: *topic, AfterID: *afterID, OnlyUnprocessed: *unprocessed}
+	var err error
+	if filter.From, err = parseTime(*from); err != nil {
+		return fmt.Errorf("-from: %w", err)
+	}
+	if filter.To, err = parseTime(*to); err != nil {
+		return fmt.Errorf("-to: %w", err)
+	}
+	if err = filter.ValidateWindow(); err != nil {
+		return err
+	}
+
+	env := setup()
+	defer env.close()
+	if !isMessageType(*topic) {
+		return errors.New("-topic must be one of MESSAGE_TYPES")
+	}
+
+	messageService, kafkaWriter, _ := env.newMessageService()
+	defer kafkaWriter.Close()
+
+	total, err := messageService.ReprocessMessages(context.Background(), service.ReprocessParams{
+		Filter: filter,
+		Limit:  *limit,
+		DryRun: *dryRun,
+	})
+	if err != nil {
+		return err
+	}
+
+	if *dryRun {
+		fmt.Printf("Подходящих сообщений: %d\n", total)
+	} else {
+		fmt.Printf("Повторно опубликовано сообщений: %d\n", total)
+	}
+	return nil
+}
+
+// runResetOffsets перемещает смещения группы консьюмера топика. Консьюмеры топика должны быть остановлены.
+func runResetOffsets(args []string) error {
+	flags := flag.NewFlagSet("reset-offsets", flag.ExitOnError)
+	topic := flags.String("topic", "", "тип сообщений (обязательно)")
+	position := flags.String("to", "", "earliest или latest")
+	offset := flags.Int64("to-offset", -1, "смещение для всех разделов")
+	timestamp := flags.String("to-time", "", "первое сообщение не раньше времени (RFC3339)")
+	partition := flags.Int("partition", -1, "только один раздел")
+	reprocess := flags.Bool("reprocess", false, "повторно обработать уже обработанные сообщения начиная с -to-time")
+	priority := flags.String("priority", "", "уровень приоритета high, normal или low (по умолчанию normal)")
+	_ = flags.Parse(args)
+
+	params := service.ResetOffsetsParams{MessageType: *topic, Position: *position, Reprocess: *reprocess, Priority: *priority}
+	if *offset >= 0 {
+		params.Offset = offset
+	}
+	if *partition >= 0 {
+		params.Partition = partition
+	}
+	if *timestamp != "" {
+		at, err := time.Parse(time.RFC3339, *timestamp)
+		if err != nil {
+			return fmt.Errorf("-to-time: %w", err)
+		}
+		params.Timestamp = &at
+	}
+	if err := params.Validate(); err != nil {
+		return err
+	}
+
+	env := setup()
+	defer env.close()
+	if !isMessageType(*topic) {
+		return errors.New("-topic must be one of MESSAGE_TYPES")
+	}
+
+	messageService, kafkaWriter, _ := env.newMessageService()
+	defer kafkaWriter.Close()
+
+	result, err := messageService.ResetOffsets(context.Background(), params)
+	if err != nil {
+		return err
+	}
+	for _, partition := range result.Partitions {
+		fmt.Printf("%s [%d]: %d -> %d\n", result.Topic, partition.Partition, partition.Previous, partition.Offset)
+	}
+	if *reprocess {
+		fmt.Printf("Отмечено для повторной обработки: %d\n", result.Reprocessed)
+	}
+	return nil
+}
+
+// isMessageType сообщает, входит ли тип в MESSAGE_TYPES.
+func isMessageType(topic string) bool {
+	for _, messageType := range config.MessageTypes() {
+		if messageType == topic {
+			return true
+		}
+	}
+	return false
+}
+
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
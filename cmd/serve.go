@@ -0,0 +1,313 @@
+package main
+
+import (
+	"ProjectMessageService/config"
+	"ProjectMessageService/internal/handler"
+	"ProjectMessageService/internal/repository"
+	"ProjectMessageService/internal/service"
+	"ProjectMessageService/internal/tracing"
+	"ProjectMessageService/util"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+)
+
+// runOptions - что запускает процесс: HTTP API, консьюмеры Kafka или оба.
+type runOptions struct {
+	api       bool
+	consumers bool
+	migrate   bool
+	addr      string // Адрес HTTP API или, без API, служебного сервера с /metrics, /healthz и /readyz
+}
+
+func runServe(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	consumers := flags.Bool("consumers", false, "также запустить консьюмеры Kafka")
+	migrate := flags.Bool("migrate", true, "выполнить миграции при запуске")
+	addr := flags.String("addr", "", "адрес HTTP API, по умолчанию HTTP_ADDR")
+	_ = flags.Parse(args)
+
+	return run(runOptions{api: true, consumers: *consumers, migrate: *migrate, addr: *addr})
+}
+
+func runConsume(args []string) error {
+	flags := flag.NewFlagSet("consume", flag.ExitOnError)
+	migrate := flags.Bool("migrate", true, "выполнить миграции при запуске")
+	addr := flags.String("addr", ":9090", "адрес /metrics, /healthz и /readyz, пустой - не запускать")
+	_ = flags.Parse(args)
+
+	return run(runOptions{consumers: true, migrate: *migrate, addr: *addr})
+}
+
+func run(opts runOptions) error {
+	env := setup()
+	app, cfg, settings := env.app, env.cfg, env.settings
+	defer env.close()
+
+	if opts.migrate {
+		env.migrate()
+	}
+
+	messageService, kafkaWriter, webhooks := env.newMessageService()
+	if err := checkPriorityTopics(messageService, app); err != nil {
+		_ = kafkaWriter.Close()
+		return err
+	}
+	newHandler := handler.NewHandler(settings, messageService, env.repo, app)
+	var srv *http.Server
+	var err error
+	switch {
+	case opts.api:
+		addr := opts.addr
+		if addr == "" {
+			addr = cfg.HTTPAddr
+		}
+		srv, err = newHTTPServer(cfg, addr, newRouter(newHandler, settings))
+	case opts.addr != "":
+		srv, err = newHTTPServer(cfg, opts.addr, newProbeRouter(newHandler))
+	}
+	if err != nil {
+		_ = kafkaWriter.Close()
+		return err
+	}
+
+	webhooksCtx, stopWebhooks := context.WithCancel(context.Background())
+	webhooksDone := make(chan struct{})
+	go func() {
+		webhooks.Run(webhooksCtx)
+		close(webhooksDone)
+	}()
+
+	// Планировщик отложенных сообщений работает в каждом процессе, реплики не публикуют одно сообщение дважды
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	schedulerDone := make(chan struct{})
+	go func() {
+		messageService.RunScheduler(schedulerCtx)
+		close(schedulerDone)
+	}()
+
+	// Удаление истекших сообщений; удаление пачками безопасно при нескольких репликах
+	purgeCtx, stopPurge := context.WithCancel(context.Background())
+	purgeDone := make(chan struct{})
+	go func() {
+		messageService.RunExpiryPurge(purgeCtx, cfg.MessageTTLPurgeDelay, cfg.RetentionArchiveDir)
+		close(purgeDone)
+	}()
+
+	// Очистка топиков по политикам хранения RETENTION
+	retentionCtx, stopRetention := context.WithCancel(context.Background())
+	retentionDone := make(chan struct{})
+	go func() {
+		messageService.RunRetention(retentionCtx, service.RetentionOptions{
+			Interval:   cfg.RetentionInterval,
+			BatchSize:  cfg.RetentionBatchSize,
+			ArchiveDir: cfg.RetentionArchiveDir,
+		})
+		close(retentionDone)
+	}()
+
+	consumeCtx, stopConsumers := context.WithCancel(context.Background())
+	if opts.consumers {
+		messageService.ConsumeMessages(consumeCtx, cfg, config.MessageTypes())
+	}
+
+	// Параметры с тегом reload применяются при изменении файла конфигурации
+	settings.Subscribe(func(e config.ChangeEvent) {
+		if e.Has("LOG_DEBUG", "LOG_INFO", "LOG_WARN") {
+			config.ApplyLogLevels(e.New)
+		}
+		if e.Has("MESSAGE_TTL") {
+			// Значение проверено при чтении конфигурации
+			ttls, _ := e.New.TopicTTLs()
+			messageService.SetTopicTTLs(ttls)
+		}
+		if e.Has("RETENTION") {
+			policies, _ := e.New.RetentionPolicies()
+			messageService.SetRetentionPolicies(policies)
+		}
+		if e.Has("MESSAGE_TYPES") {
+			added := addedTopics(e.Old.MessageTypes, e.New.MessageTypes)
+			if err := repository.RunTopicMigrations(env.db, added); err != nil {
+				app.Log.Errorf("Не удалось создать таблицы топиков %v: %v", added, err)
+				return
+			}
+			// Удаленные топики перестают приниматься API, их консьюмеры работают до перезапуска
+			config.SetMessageTypes(e.New.MessageTypes)
+			if opts.consumers {
+				messageService.ConsumeMessages(consumeCtx, cfg, added)
+			}
+		}
+	})
+	settings.Watch()
+
+	if srv != nil {
+		srv.RegisterOnShutdown(messageService.StopConsumeWaits)
+		go func() {
+			if err := listenAndServe(srv); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				app.Log.Fatalf("Не удалось запустить сервер: %v", err)
+			}
+		}()
+	}
+
+	signalCtx, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+	<-signalCtx.Done()
+	app.Log.Infof("Получен сигнал остановки, завершение работы (не дольше %s)", cfg.ShutdownTimeout)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	// Перестаем принимать запросы и дожидаемся завершения текущих
+	if srv != nil {
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			app.Log.Errorf("HTTP-сервер остановлен с ошибкой: %v", err)
+		}
+	}
+
+	// Консьюмеры дообрабатывают текущее сообщение и закрывают читателей
+	stopConsumers()
+	consumersDone := make(chan struct{})
+	go func() {
+		messageService.WaitConsumers()
+		close(consumersDone)
+	}()
+	waitDone(shutdownCtx, app, "консьюмеров Kafka", consumersDone)
+
+	// Планировщик завершает публикацию текущей пачки
+	stopScheduler()
+	waitDone(shutdownCtx, app, "планировщика отложенных сообщений", schedulerDone)
+
+	// Задачи повторной обработки прерываются, их можно запустить заново с after_id
+	messageService.StopReprocessJobs()
+
+	stopPurge()
+	waitDone(shutdownCtx, app, "удаления истекших сообщений", purgeDone)
+	stopRetention()
+	waitDone(shutdownCtx, app, "очистки по политикам хранения", retentionDone)
+
+	// Отправляем буферизованные сообщения
+	if err := kafkaWriter.Close(); err != nil {
+		app.Log.Errorf("Не удалось закрыть писателя Kafka: %v", err)
+	}
+
+	stopWebhooks()
+	waitDone(shutdownCtx, app, "доставки вебхуков", webhooksDone)
+
+	app.Log.Infof("Сервис остановлен")
+	return nil
+}
+
+// checkPriorityTopics проверяет, что топики уровней приоритета из PRIORITIES созданы: без них публикация
+// и консьюмеры типа не работают. Если Kafka недоступна, запуск продолжается, как и без проверки.
+func checkPriorityTopics(messageService *service.MessageService, app *config.Application) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	missing, err := messageService.MissingPriorityTopics(ctx)
+	if err != nil {
+		app.Log.Warnf("Не удалось проверить топики уровней приоритета: %v", err)
+		return nil
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("priority topics %v do not exist, create them with topics create -priorities", missing)
+	}
+	return nil
+}
+
+func newRouter(newHandler *handler.Handler, settings *config.Store) *gin.Engine {
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.Default()
+	// gin.Context передает значения контекста запроса (идентификатор запроса, пользователь, трасса)
+	r.ContextWithFallback = true
+	r.Use(handler.RequestIDMiddleware())
+	r.Use(otelgin.Middleware(tracing.ServiceName))
+	r.Use(handler.MetricsMiddleware())
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	r.GET("/healthz", newHandler.Healthz)
+	r.GET("/readyz", newHandler.Readyz)
+	r.POST("/users", newHandler.CreateUser)
+	r.POST("/users/login", newHandler.LoginUser)
+	r.POST("/token/renew_access", newHandler.RenewAccessToken)
+
+	cfg := settings.Get()
+	rateLimit := handler.RateLimitMiddleware(settings)
+
+	// Публиковать могут и внутренние сервисы с клиентским сертификатом (mTLS)
+	publishRoutes := r.Group("/").Use(handler.ClientCertAuthMiddleware(newHandler.TokenMaker), rateLimit)
+	publishRoutes.POST("/messages", handler.BodyLimitMiddleware(cfg.HTTPMaxBodyBytes), newHandler.CreateMessage)
+	publishRoutes.POST("/messages/batch", handler.BodyLimitMiddleware(cfg.HTTPMaxBatchBodyBytes), newHandler.CreateMessageBatch)
+	publishRoutes.GET("/messages/:id", newHandler.GetScheduledMessage)
+	publishRoutes.DELETE("/messages/:id", newHandler.CancelScheduledMessage)
+
+	authRoutes := r.Group("/").Use(handler.AuthMiddleware(newHandler.TokenMaker), rateLimit)
+	authRoutes.GET("/stats", newHandler.GetStats)
+	authRoutes.GET("/topics/:topic/stats", newHandler.GetTopicStats)
+	authRoutes.POST("/topics/:topic/consume", newHandler.Consume)
+	authRoutes.POST("/topics/:topic/ack", newHandler.Ack)
+	authRoutes.POST("/topics/:topic/nack", newHandler.Nack)
+
+	adminRoutes := r.Group("/").Use(handler.AuthMiddleware(newHandler.TokenMaker), handler.RoleMiddleware(util.AdminRole))
+	adminRoutes.GET("/status", newHandler.Status)
+	adminRoutes.GET("/admin/config", newHandler.GetConfig)
+	// Вебхук отправляет запросы на произвольный адрес, поэтому регистрировать их может только администратор
+	adminRoutes.POST("/topics/:topic/webhooks", newHandler.CreateWebhook)
+	adminRoutes.POST("/topics/:topic/schemas", newHandler.RegisterSchema)
+	adminRoutes.GET("/topics/:topic/schemas/latest", newHandler.GetSchema)
+	adminRoutes.GET("/admin/consumers/lag", newHandler.GetConsumerLag)
+	adminRoutes.GET("/admin/topics/:topic/lag", newHandler.GetTopicConsumerLag)
+	adminRoutes.POST("/admin/topics/:topic/offsets", newHandler.ResetOffsets)
+	adminRoutes.POST("/admin/topics/:topic/reprocess", newHandler.Reprocess)
+	adminRoutes.GET("/admin/reprocess/:id", newHandler.GetReprocessJob)
+	adminRoutes.GET("/admin/routing/rules", newHandler.ListRoutingRules)
+	adminRoutes.POST("/admin/routing/rules", newHandler.CreateRoutingRule)
+	adminRoutes.PATCH("/admin/routing/rules/:id", newHandler.SetRoutingRuleActive)
+	adminRoutes.DELETE("/admin/routing/rules/:id", newHandler.DeleteRoutingRule)
+	adminRoutes.POST("/admin/routing/test", newHandler.TestRouting)
+	return r
+}
+
+// newProbeRouter - служебные маршруты процесса consume для Prometheus и оркестратора.
+func newProbeRouter(newHandler *handler.Handler) *gin.Engine {
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(gin.Recovery())
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	r.GET("/healthz", newHandler.Healthz)
+	r.GET("/readyz", newHandler.Readyz)
+	return r
+}
+
+// addedTopics возвращает типы сообщений из next, которых нет в previous.
+func addedTopics(previous, next []string) []string {
+	var added []string
+	for _, topic := range next {
+		found := false
+		for _, old := range previous {
+			if old == topic {
+				found = true
+				break
+			}
+		}
+		if !found {
+			added = append(added, topic)
+		}
+	}
+	return added
+}
+
+// waitDone ждет закрытия done, но не дольше срока остановки.
+func waitDone(ctx context.Context, app *config.Application, name string, done <-chan struct{}) {
+	select {
+	case <-done:
+	case <-ctx.Done():
+		app.Log.Errorf("Срок остановки истек до завершения %s", name)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"ProjectMessageService/config"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// newHTTPServer настраивает http.Server по параметрам HTTP_* и TLS_* конфигурации.
+func newHTTPServer(cfg config.Config, addr string, handler http.Handler) (*http.Server, error) {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadTimeout:       cfg.HTTPReadTimeout,
+		ReadHeaderTimeout: cfg.HTTPReadHeaderTimeout,
+		WriteTimeout:      cfg.HTTPWriteTimeout,
+		IdleTimeout:       cfg.HTTPIdleTimeout,
+		MaxHeaderBytes:    cfg.HTTPMaxHeaderBytes,
+	}
+
+	if cfg.TLSCertFile == "" {
+		if cfg.HTTP2Enabled {
+			// HTTP/2 без TLS (h2c) для клиентов внутри кластера
+			srv.Handler = h2c.NewHandler(handler, &http2.Server{IdleTimeout: cfg.HTTPIdleTimeout})
+		}
+		return srv, nil
+	}
+
+	tlsConfig, err := newTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	srv.TLSConfig = tlsConfig
+	if !cfg.HTTP2Enabled {
+		// Непустая карта отключает автоматическое согласование h2
+		srv.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler))
+	}
+	return srv, nil
+}
+
+func newTLSConfig(cfg config.Config) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load TLS certificate: %w", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	switch cfg.TLSClientAuth {
+	case config.TLSClientAuthOptional:
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	case config.TLSClientAuthRequire:
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return tlsConfig, nil
+	}
+
+	caPEM, err := os.ReadFile(cfg.TLSClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read TLS client CA: %w", err)
+	}
+	tlsConfig.ClientCAs = x509.NewCertPool()
+	if !tlsConfig.ClientCAs.AppendCertsFromPEM(caPEM) {
+		return nil, errors.New("TLS client CA file contains no certificates")
+	}
+	return tlsConfig, nil
+}
+
+// listenAndServe запускает сервер по HTTPS, если настроен сертификат, иначе по HTTP.
+func listenAndServe(srv *http.Server) error {
+	if srv.TLSConfig != nil {
+		// Сертификат уже загружен в TLSConfig
+		return srv.ListenAndServeTLS("", "")
+	}
+	return srv.ListenAndServe()
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"ProjectMessageService/config"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestCert создает самоподписанный сертификат и ключ в dir и возвращает пути к ним.
+func writeTestCert(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCert(t, dir)
+	cfg := config.Config{TLSCertFile: certFile, TLSKeyFile: keyFile}
+
+	tlsConfig, err := newTLSConfig(cfg)
+	require.NoError(t, err)
+	require.Len(t, tlsConfig.Certificates, 1)
+	require.Equal(t, uint16(tls.VersionTLS12), tlsConfig.MinVersion)
+	require.Equal(t, tls.NoClientCert, tlsConfig.ClientAuth)
+	require.Nil(t, tlsConfig.ClientCAs)
+
+	// Клиентский сертификат проверяется по TLS_CLIENT_CA_FILE
+	cfg.TLSClientCAFile = certFile
+	cfg.TLSClientAuth = config.TLSClientAuthOptional
+	tlsConfig, err = newTLSConfig(cfg)
+	require.NoError(t, err)
+	require.Equal(t, tls.VerifyClientCertIfGiven, tlsConfig.ClientAuth)
+	require.NotNil(t, tlsConfig.ClientCAs)
+
+	cfg.TLSClientAuth = config.TLSClientAuthRequire
+	tlsConfig, err = newTLSConfig(cfg)
+	require.NoError(t, err)
+	require.Equal(t, tls.RequireAndVerifyClientCert, tlsConfig.ClientAuth)
+
+	// CA без сертификатов и отсутствующие файлы - ошибка
+	cfg.TLSClientCAFile = keyFile
+	_, err = newTLSConfig(cfg)
+	require.Error(t, err)
+	cfg.TLSClientCAFile = filepath.Join(dir, "missing.pem")
+	_, err = newTLSConfig(cfg)
+	require.Error(t, err)
+	_, err = newTLSConfig(config.Config{TLSCertFile: certFile, TLSKeyFile: filepath.Join(dir, "missing.pem")})
+	require.Error(t, err)
+}
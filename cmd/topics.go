@@ -0,0 +1,151 @@
+package main
+
+import (
+	"ProjectMessageService/config"
+	"ProjectMessageService/internal/repository"
+	"ProjectMessageService/internal/service"
+	"ProjectMessageService/internal/utils"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"text/tabwriter"
+
+	"github.com/segmentio/kafka-go"
+)
+
+func runTopics(args []string) error {
+	if len(args) == 0 {
+		return errors.New("expected subcommand: list or create")
+	}
+
+	switch args[0] {
+	case "list":
+		return runTopicsList(args[1:])
+	case "create":
+		return runTopicsCreate(args[1:])
+	}
+	return fmt.Errorf("unknown subcommand %q, expected list or create", args[0])
+}
+
+// runTopicsList выводит настроенные типы сообщений с количеством сообщений в базе и разделами Kafka.
+func runTopicsList(args []string) error {
+	flags := flag.NewFlagSet("topics list", flag.ExitOnError)
+	_ = flags.Parse(args)
+
+	env := setup()
+	defer env.close()
+	ctx := context.Background()
+
+	client := &kafka.Client{Addr: kafka.TCP(env.cfg.KafkaBrokers...)}
+	metadata, err := client.Metadata(ctx, &kafka.MetadataRequest{})
+	if err != nil {
+		return fmt.Errorf("cannot read Kafka metadata: %w", err)
+	}
+	partitions := make(map[string]int)
+	for _, topic := range metadata.Topics {
+		partitions[topic.Name] = len(topic.Partitions)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TYPE\tKAFKA TOPIC\tPARTITIONS\tTABLE\tTOTAL\tPROCESSED")
+	for _, messageType := range config.MessageTypes() {
+		counts, err := env.repo.CountMessages(ctx, messageType)
+		if err != nil {
+			return err
+		}
+		kafkaTopic := service.KafkaTopic(messageType)
+		fmt.Fprintf(w, "%s\t%s\t%d\t%t\t%d\t%d\n", messageType, kafkaTopic, partitions[kafkaTopic],
+			counts.TableExists, counts.Total, counts.Processed)
+	}
+	return w.Flush()
+}
+
+// runTopicsCreate создает таблицу и топик Kafka для нового типа сообщений.
+func runTopicsCreate(args []string) error {
+	flags := flag.NewFlagSet("topics create", flag.ExitOnError)
+	partitions := flags.Int("partitions", 1, "количество разделов топика Kafka")
+	replication := flags.Int("replication", 1, "фактор репликации топика Kafka")
+	priorities := flags.Bool("priorities", false, "создать также топики уровней приоритета high и low (PRIORITIES)")
+	_ = flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return errors.New("usage: topics create [-partitions N] [-replication N] [-priorities] <type>")
+	}
+	messageType := flags.Arg(0)
+	if !config.ValidMessageType(messageType) {
+		return fmt.Errorf("invalid message type %q", messageType)
+	}
+
+	env := setup()
+	defer env.close()
+
+	if err := repository.RunTopicMigrations(env.db, []string{messageType}); err != nil {
+		return err
+	}
+	kafkaTopics := []string{service.KafkaTopic(messageType)}
+	if *priorities {
+		kafkaTopics = append(kafkaTopics,
+			service.PriorityKafkaTopic(messageType, utils.PriorityHigh),
+			service.PriorityKafkaTopic(messageType, utils.PriorityLow))
+	}
+	for _, kafkaTopic := range kafkaTopics {
+		if err := createKafkaTopic(env.cfg.KafkaBrokers, kafkaTopic, *partitions, *replication); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Топик %s создан. Добавьте его в MESSAGE_TYPES, чтобы API и консьюмеры начали с ним работать\n", messageType)
+	return nil
+}
+
+// createKafkaTopic создает топик через контроллер кластера. Существующий топик не считается ошибкой.
+func createKafkaTopic(brokers []string, topic string, partitions, replication int) error {
+	controller, err := findController(brokers, brokerController)
+	if err != nil {
+		return err
+	}
+	controllerConn, err := kafka.Dial("tcp", net.JoinHostPort(controller.Host, fmt.Sprint(controller.Port)))
+	if err != nil {
+		return err
+	}
+	defer controllerConn.Close()
+
+	err = controllerConn.CreateTopics(kafka.TopicConfig{
+		Topic:             topic,
+		NumPartitions:     partitions,
+		ReplicationFactor: replication,
+	})
+	if errors.Is(err, kafka.TopicAlreadyExists) {
+		return nil
+	}
+	return err
+}
+
+// findController опрашивает брокеры по очереди, пока один из них не сообщит контроллер кластера.
+func findController(brokers []string, controller func(broker string) (kafka.Broker, error)) (kafka.Broker, error) {
+	var errs []error
+	for _, broker := range brokers {
+		result, err := controller(broker)
+		if err == nil {
+			return result, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", broker, err))
+	}
+	if len(errs) == 0 {
+		return kafka.Broker{}, errors.New("no Kafka brokers configured")
+	}
+	return kafka.Broker{}, fmt.Errorf("cannot find Kafka controller: %w", errors.Join(errs...))
+}
+
+// brokerController запрашивает у брокера адрес контроллера кластера.
+func brokerController(broker string) (kafka.Broker, error) {
+	conn, err := kafka.Dial("tcp", broker)
+	if err != nil {
+		return kafka.Broker{}, err
+	}
+	defer conn.Close()
+	return conn.Controller()
+}
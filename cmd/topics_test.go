@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindController(t *testing.T) {
+	var asked []string
+	controller := func(broker string) (kafka.Broker, error) {
+		asked = append(asked, broker)
+		if broker == "kafka-2:9092" {
+			return kafka.Broker{Host: "kafka-3", Port: 9092, ID: 3}, nil
+		}
+		return kafka.Broker{}, errors.New("connection refused")
+	}
+
+	// Недоступный первый брокер не мешает найти контроллер
+	broker, err := findController([]string{"kafka-1:9092", "kafka-2:9092", "kafka-3:9092"}, controller)
+	require.NoError(t, err)
+	require.Equal(t, "kafka-3", broker.Host)
+	require.Equal(t, []string{"kafka-1:9092", "kafka-2:9092"}, asked)
+
+	_, err = findController([]string{"kafka-1:9092", "kafka-4:9092"}, controller)
+	require.ErrorContains(t, err, "kafka-1:9092: connection refused")
+	require.ErrorContains(t, err, "kafka-4:9092: connection refused")
+
+	_, err = findController(nil, controller)
+	require.Error(t, err)
+}
+
+func TestParseTime(t *testing.T) {
+	at, err := parseTime("")
+	require.NoError(t, err)
+	require.True(t, at.IsZero())
+
+	at, err = parseTime("2024-05-01T12:00:00Z")
+	require.NoError(t, err)
+	require.Equal(t, 2024, at.Year())
+
+	_, err = parseTime("yesterday")
+	require.Error(t, err)
+}
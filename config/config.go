@@ -2,7 +2,12 @@ package config
 
 import (
 	"ProjectMessageService/internal/loggers"
+	"fmt"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 	`time`
 
 	"github.com/gookit/slog"
@@ -13,75 +18,236 @@ type Application struct {
 	Log *slog.Logger
 }
 
-var MessageTypes = []string{"message", "ping"} // Список типов сообщений
+var (
+	messageTypesMu sync.RWMutex
+	messageTypes   = []string{"message", "ping"} // Список типов сообщений
+)
+
+// MessageTypes возвращает копию текущего списка типов сообщений (топиков).
+func MessageTypes() []string {
+	messageTypesMu.RLock()
+	defer messageTypesMu.RUnlock()
+	return append([]string(nil), messageTypes...)
+}
+
+// SetMessageTypes заменяет список типов сообщений, например после перезагрузки конфигурации.
+func SetMessageTypes(types []string) {
+	messageTypesMu.Lock()
+	defer messageTypesMu.Unlock()
+	messageTypes = append([]string(nil), types...)
+}
 
 func SetupApplication() *Application {
-	// Настройка логгера перед его инициализацией
-	loggers.SetLogConsole(true) // Логи будут записываться в файл
-	loggers.SetIsDebugMode(true)
-	loggers.SetIsInfoMode(true)
-	loggers.SetIsWarnMode(true)
-	// Настраиваем логгер
+	// До чтения конфигурации используется логгер по умолчанию (консоль, все уровни),
+	// настройки из Config применяются в ApplyLogConfig
 	logger := loggers.SetupLogger()
 	// Создаем экземпляр Application с настроенным логгером
 	return &Application{Log: logger}
 }
 
+// ApplyLogConfig перенастраивает логгер приложения по параметрам LOG_* из конфигурации.
+// Предыдущий логгер закрывается, чтобы не оставался открытым его файл журнала.
+func (a *Application) ApplyLogConfig(cfg Config) {
+	loggers.SetLogConsole(cfg.LogConsole)
+	loggers.SetLogFormat(cfg.LogFormat)
+	loggers.SetLogFile(cfg.LogFile)
+	loggers.SetRotation(loggers.Rotation{
+		Interval:   cfg.LogRotateTime,
+		MaxSize:    cfg.LogMaxSizeMB << 20,
+		Compress:   cfg.LogCompress,
+		MaxAge:     cfg.LogMaxAge,
+		MaxBackups: cfg.LogMaxBackups,
+	})
+	ApplyLogLevels(cfg)
+	// Настраиваем логгер
+	previous := a.Log
+	a.Log = loggers.SetupLogger()
+	if previous != nil {
+		_ = previous.Close()
+	}
+}
+
+// ApplyLogLevels включает и выключает уровни журнала, действует и на уже созданный логгер.
+func ApplyLogLevels(cfg Config) {
+	loggers.SetIsDebugMode(cfg.LogDebug)
+	loggers.SetIsInfoMode(cfg.LogInfo)
+	loggers.SetIsWarnMode(cfg.LogWarn)
+}
+
+// Режимы проверки клиентских сертификатов (TLS_CLIENT_AUTH).
+const (
+	TLSClientAuthNone     = "none"     // сертификаты не запрашиваются
+	TLSClientAuthOptional = "optional" // предъявленный сертификат проверяется по TLS_CLIENT_CA_FILE
+	TLSClientAuthRequire  = "require"  // без проверенного сертификата соединение отклоняется
+)
+
+// Config - параметры сервиса. Тег reload отмечает параметры, которые применяются без перезапуска,
+// тег secret - параметры, скрываемые в GET /admin/config.
 type Config struct {
-	DBHost               string        `mapstructure:"DB_HOST"`
-	DBPort               string        `mapstructure:"DB_PORT"`
-	DBUser               string        `mapstructure:"DB_USER"`
-	DBPassword           string        `mapstructure:"DB_PASSWORD"`
-	DBName               string        `mapstructure:"DB_NAME"`
-	KafkaURL             string        `mapstructure:"KAFKA_URL"`
-	TokenSymmetricKey    string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
-	AccessTokenDuration  time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
-	RefreshTokenDuration time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
-	EmailSenderName      string        `mapstructure:"EMAIL_SENDER_NAME"`
-	EmailSenderAddress   string        `mapstructure:"EMAIL_SENDER_ADDRESS"`
-	EmailSenderPassword  string        `mapstructure:"EMAIL_SENDER_PASSWORD"`
+	DBHost                string        `mapstructure:"DB_HOST"`
+	DBPort                string        `mapstructure:"DB_PORT"`
+	DBUser                string        `mapstructure:"DB_USER"`
+	DBPassword            string        `mapstructure:"DB_PASSWORD" secret:"true"`
+	DBName                string        `mapstructure:"DB_NAME"`
+	KafkaBrokers          []string      `mapstructure:"KAFKA_BROKERS"`               // Адреса брокеров host:port, через запятую в env
+	KafkaURL              string        `mapstructure:"KAFKA_URL"`                   // Устарело: один брокер, используется, если KAFKA_BROKERS не задан
+	MessageTypes          []string      `mapstructure:"MESSAGE_TYPES" reload:"true"` // Типы сообщений (топики)
+	TokenSymmetricKey     string        `mapstructure:"TOKEN_SYMMETRIC_KEY" secret:"true"`
+	AccessTokenDuration   time.Duration `mapstructure:"ACCESS_TOKEN_DURATION" reload:"true"`
+	RefreshTokenDuration  time.Duration `mapstructure:"REFRESH_TOKEN_DURATION" reload:"true"`
+	RateLimitRPS          float64       `mapstructure:"RATE_LIMIT_RPS" reload:"true"`   // Запросов в секунду на пользователя, 0 - без ограничения
+	RateLimitBurst        int           `mapstructure:"RATE_LIMIT_BURST" reload:"true"` // Допустимый всплеск запросов
+	EmailSenderName       string        `mapstructure:"EMAIL_SENDER_NAME"`
+	EmailSenderAddress    string        `mapstructure:"EMAIL_SENDER_ADDRESS"`
+	EmailSenderPassword   string        `mapstructure:"EMAIL_SENDER_PASSWORD" secret:"true"`
+	TracingExporter       string        `mapstructure:"TRACING_EXPORTER"`            // none, otlp или stdout
+	OTLPEndpoint          string        `mapstructure:"OTEL_EXPORTER_OTLP_ENDPOINT"` // URL OTLP/HTTP коллектора
+	LogConsole            bool          `mapstructure:"LOG_CONSOLE"`                 // false - писать логи в LOG_FILE
+	LogFormat             string        `mapstructure:"LOG_FORMAT"`                  // text или json
+	LogDebug              bool          `mapstructure:"LOG_DEBUG" reload:"true"`
+	LogInfo               bool          `mapstructure:"LOG_INFO" reload:"true"`
+	LogWarn               bool          `mapstructure:"LOG_WARN" reload:"true"`
+	LogFile               string        `mapstructure:"LOG_FILE"`
+	LogRotateTime         time.Duration `mapstructure:"LOG_ROTATE_TIME"` // 0 - без ротации по времени
+	LogMaxSizeMB          uint64        `mapstructure:"LOG_MAX_SIZE_MB"` // 0 - без ротации по размеру
+	LogCompress           bool          `mapstructure:"LOG_COMPRESS"`
+	LogMaxAge             time.Duration `mapstructure:"LOG_MAX_AGE"`      // 0 - хранить без ограничения по времени
+	LogMaxBackups         uint          `mapstructure:"LOG_MAX_BACKUPS"`  // 0 - без ограничения по количеству
+	ShutdownTimeout       time.Duration `mapstructure:"SHUTDOWN_TIMEOUT"` // Общий срок корректной остановки сервиса
+	HTTPAddr              string        `mapstructure:"HTTP_ADDR"`
+	HTTPReadTimeout       time.Duration `mapstructure:"HTTP_READ_TIMEOUT"`
+	HTTPReadHeaderTimeout time.Duration `mapstructure:"HTTP_READ_HEADER_TIMEOUT"`
+	HTTPWriteTimeout      time.Duration `mapstructure:"HTTP_WRITE_TIMEOUT"` // Должен превышать ожидание long-poll консьюмеров
+	HTTPIdleTimeout       time.Duration `mapstructure:"HTTP_IDLE_TIMEOUT"`
+	HTTPMaxHeaderBytes    int           `mapstructure:"HTTP_MAX_HEADER_BYTES"`
+	HTTPMaxBodyBytes      int64         `mapstructure:"HTTP_MAX_BODY_BYTES"`       // Лимит тела POST /messages
+	HTTPMaxBatchBodyBytes int64         `mapstructure:"HTTP_MAX_BATCH_BODY_BYTES"` // Лимит тела POST /messages/batch
+	HTTP2Enabled          bool          `mapstructure:"HTTP2_ENABLED"`             // HTTP/2 по TLS или h2c без TLS
+	TLSCertFile           string        `mapstructure:"TLS_CERT_FILE"`             // Сертификат сервера, включает HTTPS
+	TLSKeyFile            string        `mapstructure:"TLS_KEY_FILE"`
+	TLSClientCAFile       string        `mapstructure:"TLS_CLIENT_CA_FILE"`        // CA для проверки клиентских сертификатов
+	TLSClientAuth         string        `mapstructure:"TLS_CLIENT_AUTH"`           // none, optional или require
+	Processors            string        `mapstructure:"PROCESSORS"`                // Конвейеры топиков: message=validate,trim;ping=enrich
+	MessageTTL            string        `mapstructure:"MESSAGE_TTL" reload:"true"` // Срок жизни сообщений топиков: ping=5m;message=24h
+	MessageTTLPurgeDelay  time.Duration `mapstructure:"MESSAGE_TTL_PURGE_DELAY"`   // Сколько хранить истекшие сообщения перед удалением
+	Retention             string        `mapstructure:"RETENTION" reload:"true"`   // Хранение сообщений топиков: message=max_age:720h,max_rows:100000,keep_unprocessed
+	RetentionInterval     time.Duration `mapstructure:"RETENTION_INTERVAL"`        // Как часто применяются политики хранения
+	RetentionBatchSize    int           `mapstructure:"RETENTION_BATCH_SIZE"`      // Сколько сообщений удаляется одним запросом
+	RetentionArchiveDir   string        `mapstructure:"RETENTION_ARCHIVE_DIR"`     // Каталог архивов удаленных сообщений, пусто - без архива
+	Priorities            string        `mapstructure:"PRIORITIES"`                // Топики уровней приоритета и их веса: message=high:6,normal:3,low:1
+	Workers               string        `mapstructure:"CONSUMER_WORKERS"`          // Обработчики консьюмеров топиков: message=8;ping=2, по умолчанию 1
 }
 
+// setDefaults задает значения по умолчанию, чтобы их можно было не указывать в app.env.
+func setDefaults(v *viper.Viper) {
+	v.SetDefault("MESSAGE_TYPES", []string{"message", "ping"})
+	v.SetDefault("RATE_LIMIT_RPS", 0)
+	v.SetDefault("RATE_LIMIT_BURST", 20)
+	v.SetDefault("SHUTDOWN_TIMEOUT", 30*time.Second)
+	v.SetDefault("HTTP_ADDR", ":8080")
+	v.SetDefault("HTTP_READ_TIMEOUT", 15*time.Second)
+	v.SetDefault("HTTP_READ_HEADER_TIMEOUT", 5*time.Second)
+	v.SetDefault("HTTP_WRITE_TIMEOUT", 75*time.Second)
+	v.SetDefault("HTTP_IDLE_TIMEOUT", 120*time.Second)
+	v.SetDefault("HTTP_MAX_HEADER_BYTES", 1<<20)
+	v.SetDefault("HTTP_MAX_BODY_BYTES", 1<<20)
+	v.SetDefault("HTTP_MAX_BATCH_BODY_BYTES", 16<<20)
+	v.SetDefault("HTTP2_ENABLED", true)
+	v.SetDefault("TLS_CLIENT_AUTH", TLSClientAuthNone)
+	v.SetDefault("MESSAGE_TTL_PURGE_DELAY", time.Hour)
+	v.SetDefault("RETENTION_INTERVAL", 10*time.Minute)
+	v.SetDefault("RETENTION_BATCH_SIZE", 1000)
+	v.SetDefault("LOG_CONSOLE", true)
+	v.SetDefault("LOG_FORMAT", loggers.FormatText)
+	v.SetDefault("LOG_DEBUG", true)
+	v.SetDefault("LOG_INFO", true)
+	v.SetDefault("LOG_WARN", true)
+	v.SetDefault("LOG_FILE", "log/service.log")
+	v.SetDefault("LOG_ROTATE_TIME", 24*time.Hour)
+	v.SetDefault("LOG_MAX_SIZE_MB", 100)
+	v.SetDefault("LOG_COMPRESS", true)
+	v.SetDefault("LOG_MAX_AGE", 7*24*time.Hour)
+	v.SetDefault("LOG_MAX_BACKUPS", 30)
+}
+
+// LoadConfig читает app.env, YAML-файл из CONFIG_FILE (если задан) и переменные окружения,
+// которые имеют наивысший приоритет. Для любого параметра можно указать <KEY>_FILE с путем к файлу,
+// содержимое которого станет значением (например, DB_PASSWORD_FILE для Docker secrets).
 func LoadConfig(app *Application) (cfg Config) {
+	cfg, err := readConfig(app, viper.GetViper())
+	if err != nil {
+		app.Log.Fatalf("%v", err)
+	}
+	SetMessageTypes(cfg.MessageTypes)
+
+	return cfg
+}
+
+// readConfig читает и проверяет конфигурацию с помощью экземпляра viper v.
+func readConfig(app *Application, v *viper.Viper) (cfg Config, err error) {
 	// Чтение файла app.env
-	viper.AddConfigPath(".")
-	viper.SetConfigName("app")
-	viper.SetConfigType("env")
+	v.AddConfigPath(".")
+	v.SetConfigName("app")
+	v.SetConfigType("env")
 
 	// Чтение переменных окружения
-	viper.AutomaticEnv() // Автоматически читать переменные окружения
-
-	if err := viper.MergeInConfig(); err != nil {
-		app.Log.Printf("Error reading app.env file, %s", err)
+	v.AutomaticEnv() // Автоматически читать переменные окружения
+	// Параметры, которых нет в файлах, попадают в Unmarshal только после явной привязки
+	for _, key := range configKeys() {
+		_ = v.BindEnv(key)
 	}
+	setDefaults(v)
 
-	if err := viper.ReadInConfig(); err != nil {
-		app.Log.Fatalf("Error reading config file, %s", err)
+	if err = v.ReadInConfig(); err != nil {
+		app.Log.Warnf("Не удалось прочитать app.env: %s", err)
 	}
-	/*
-		cfg := Config{
-			DBHost:     viper.GetString("DB_HOST"),
-			DBPort:     viper.GetString("DB_PORT"),
-			DBUser:     viper.GetString("DB_USER"),
-			DBPassword: viper.GetString("DB_PASSWORD"),
-			DBName:     viper.GetString("DB_NAME"),
-			KafkaURL:   viper.GetString("KAFKA_URL"),
+
+	if path := v.GetString("CONFIG_FILE"); path != "" {
+		v.SetConfigFile(path)
+		v.SetConfigType(strings.TrimPrefix(filepath.Ext(path), "."))
+		if err = v.MergeInConfig(); err != nil {
+			return cfg, fmt.Errorf("cannot read config file %s: %w", path, err)
 		}
+	}
 
-	*/
-	err := viper.Unmarshal(&cfg)
-	if err != nil {
-		app.Log.Fatalf("unable to decode into struct, %v", err)
+	if err = loadSecretFiles(v); err != nil {
+		return cfg, err
 	}
 
-	// Проверка, запущено ли приложение внутри Docker
-	if _, err = os.Stat("/.dockerenv"); err == nil {
-		// Внутри Docker
-		cfg.KafkaURL = "kafka:9092"
-	} else {
-		// Вне Docker
-		cfg.KafkaURL = "localhost:29092"
+	if err = v.Unmarshal(&cfg); err != nil {
+		return cfg, fmt.Errorf("unable to decode into struct, %w", err)
 	}
 
-	return cfg
+	if len(cfg.KafkaBrokers) == 0 && cfg.KafkaURL != "" {
+		cfg.KafkaBrokers = strings.Split(cfg.KafkaURL, ",")
+	}
+
+	return cfg, cfg.Validate()
+}
+
+// configKeys возвращает имена всех параметров Config.
+func configKeys() []string {
+	t := reflect.TypeOf(Config{})
+	keys := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		keys = append(keys, t.Field(i).Tag.Get("mapstructure"))
+	}
+	return keys
+}
+
+// loadSecretFiles подставляет значения параметров из файлов, указанных в <KEY>_FILE.
+func loadSecretFiles(v *viper.Viper) error {
+	for _, key := range configKeys() {
+		path := v.GetString(key + "_FILE")
+		if path == "" {
+			continue
+		}
+
+		value, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("cannot read %s_FILE: %w", key, err)
+		}
+		v.Set(key, strings.TrimRight(string(value), "\r\n"))
+	}
+	return nil
 }
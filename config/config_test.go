@@ -0,0 +1,53 @@
+package config
+
+import (
+	"ProjectMessageService/internal/loggers"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageTypesCopy(t *testing.T) {
+	defer SetMessageTypes(MessageTypes())
+	SetMessageTypes([]string{"message", "ping"})
+
+	types := MessageTypes()
+	types[0] = "changed"
+	require.Equal(t, []string{"message", "ping"}, MessageTypes())
+}
+
+func TestApplyLogConfigClosesPreviousLogger(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.log")
+	second := filepath.Join(dir, "second.log")
+	defer loggers.SetLogConsole(true)
+
+	app := &Application{}
+	cfg := Config{LogFile: first, LogFormat: loggers.FormatText, LogInfo: true}
+	app.ApplyLogConfig(cfg)
+	app.Log.Info("first")
+	cfg.LogFile = second
+	app.ApplyLogConfig(cfg)
+	app.Log.Info("second")
+	defer app.Log.Close()
+
+	data, err := os.ReadFile(first)
+	require.NoError(t, err)
+	require.Contains(t, string(data), "first")
+	require.NotContains(t, string(data), "second")
+	data, err = os.ReadFile(second)
+	require.NoError(t, err)
+	require.Contains(t, string(data), "second")
+
+	// Файл предыдущего логгера закрыт
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skip("/proc is not available")
+	}
+	for _, fd := range fds {
+		target, _ := os.Readlink(filepath.Join("/proc/self/fd", fd.Name()))
+		require.NotEqual(t, first, target)
+	}
+}
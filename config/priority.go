@@ -0,0 +1,45 @@
+package config
+
+import (
+	"ProjectMessageService/internal/utils"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultPriorityWeights - веса уровней приоритета, не указанных в PRIORITIES.
+var DefaultPriorityWeights = map[string]int{utils.PriorityHigh: 6, utils.PriorityNormal: 3, utils.PriorityLow: 1}
+
+// PriorityWeights разбирает PRIORITIES вида "message=high:6,normal:3,low:1;ping=" в веса уровней приоритета
+// топиков: сколько сообщений уровня консьюмер обрабатывает за цикл. Только у перечисленных топиков есть
+// отдельные топики Kafka для high и low, сообщения остальных публикуются в основной топик независимо от приоритета.
+func (c Config) PriorityWeights() (map[string]map[string]int, error) {
+	priorities := make(map[string]map[string]int)
+	err := c.parseTopicMap("PRIORITIES", c.Priorities, "<level>:<weight>,<level>:<weight>", func(topic, options string) error {
+		weights := make(map[string]int, len(DefaultPriorityWeights))
+		for level, weight := range DefaultPriorityWeights {
+			weights[level] = weight
+		}
+		for _, option := range strings.Split(options, ",") {
+			option = strings.TrimSpace(option)
+			if option == "" {
+				continue
+			}
+			level, value, _ := strings.Cut(option, ":")
+			if _, known := DefaultPriorityWeights[level]; !known {
+				return fmt.Errorf("unknown level %q, expected high, normal or low", level)
+			}
+			weight, err := strconv.Atoi(value)
+			if err != nil || weight < 1 {
+				return fmt.Errorf("weight of %s must be a positive integer, got %q", level, value)
+			}
+			weights[level] = weight
+		}
+		priorities[topic] = weights
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return priorities, nil
+}
@@ -0,0 +1,23 @@
+package config
+
+import "strings"
+
+// ProcessorPipelines разбирает PROCESSORS вида "message=validate,trim;ping=enrich"
+// в имена шагов конвейера каждого топика.
+func (c Config) ProcessorPipelines() (map[string][]string, error) {
+	pipelines := make(map[string][]string)
+	err := c.parseTopicMap("PROCESSORS", c.Processors, "<step>,<step>", func(topic, steps string) error {
+		names := []string{}
+		for _, step := range strings.Split(steps, ",") {
+			if step = strings.TrimSpace(step); step != "" {
+				names = append(names, step)
+			}
+		}
+		pipelines[topic] = names
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pipelines, nil
+}
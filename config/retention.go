@@ -0,0 +1,62 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetentionPolicy - правила хранения сообщений топика. Нулевые MaxAge и MaxRows не ограничивают хранение.
+type RetentionPolicy struct {
+	MaxAge          time.Duration `json:"max_age"`          // Сообщения старше удаляются
+	MaxRows         int64         `json:"max_rows"`         // Сколько последних сообщений хранить
+	KeepUnprocessed bool          `json:"keep_unprocessed"` // Не удалять необработанные сообщения
+}
+
+// RetentionPolicies разбирает RETENTION вида "message=max_age:720h,max_rows:100000,keep_unprocessed;ping=max_age:24h"
+// в политики хранения топиков. Сообщения топиков, которых нет в списке, хранятся без ограничения.
+func (c Config) RetentionPolicies() (map[string]RetentionPolicy, error) {
+	policies := make(map[string]RetentionPolicy)
+	err := c.parseTopicMap("RETENTION", c.Retention, "<option>,<option>", func(topic, options string) error {
+		var policy RetentionPolicy
+		for _, option := range strings.Split(options, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(option), ":")
+			var err error
+			switch name {
+			case "":
+				continue
+			case "max_age":
+				policy.MaxAge, err = time.ParseDuration(value)
+				if err == nil && policy.MaxAge <= 0 {
+					err = errors.New("must be positive")
+				}
+			case "max_rows":
+				policy.MaxRows, err = strconv.ParseInt(value, 10, 64)
+				if err == nil && policy.MaxRows <= 0 {
+					err = errors.New("must be positive")
+				}
+			case "keep_unprocessed":
+				policy.KeepUnprocessed = true
+				if value != "" {
+					policy.KeepUnprocessed, err = strconv.ParseBool(value)
+				}
+			default:
+				err = errors.New("unknown option, expected max_age, max_rows or keep_unprocessed")
+			}
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+		if policy.MaxAge == 0 && policy.MaxRows == 0 {
+			return errors.New("needs max_age or max_rows")
+		}
+		policies[topic] = policy
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// redactedValue заменяет значения секретных параметров в выводе конфигурации.
+const redactedValue = "******"
+
+// ChangeEvent описывает изменение параметров, применяемых без перезапуска.
+type ChangeEvent struct {
+	Old     Config
+	New     Config
+	Changed []string // Имена измененных параметров, например LOG_DEBUG
+}
+
+// Has сообщает, изменился ли хотя бы один из перечисленных параметров.
+func (e ChangeEvent) Has(keys ...string) bool {
+	for _, changed := range e.Changed {
+		for _, key := range keys {
+			if changed == key {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Store хранит действующую конфигурацию и рассылает подписчикам события об ее изменении.
+type Store struct {
+	app         *Application
+	mu          sync.RWMutex
+	cfg         Config
+	subscribers []func(ChangeEvent)
+}
+
+func NewStore(cfg Config, app *Application) *Store {
+	return &Store{cfg: cfg, app: app}
+}
+
+// Get возвращает действующую конфигурацию.
+func (s *Store) Get() Config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cfg
+}
+
+// Subscribe регистрирует обработчик изменений. Обработчики вызываются последовательно
+// в горутине, обнаружившей изменение.
+func (s *Store) Subscribe(fn func(ChangeEvent)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscribers = append(s.subscribers, fn)
+}
+
+// Watch следит за файлом конфигурации (CONFIG_FILE или app.env) и при его изменении
+// перечитывает конфигурацию и применяет параметры с тегом reload.
+func (s *Store) Watch() {
+	viper.OnConfigChange(func(e fsnotify.Event) {
+		next, err := readConfig(s.app, viper.New())
+		if err != nil {
+			s.app.Log.Errorf("Конфигурация из %s не применена: %v", e.Name, err)
+			return
+		}
+		s.Update(next)
+	})
+	viper.WatchConfig()
+}
+
+// Update применяет параметры с тегом reload из next и уведомляет подписчиков.
+// Изменения остальных параметров вступят в силу только после перезапуска.
+func (s *Store) Update(next Config) {
+	s.mu.Lock()
+	old := s.cfg
+	updated := old
+	var changed, restart []string
+
+	current, target := reflect.ValueOf(&updated).Elem(), reflect.ValueOf(next)
+	for i := 0; i < current.NumField(); i++ {
+		field := current.Type().Field(i)
+		if reflect.DeepEqual(current.Field(i).Interface(), target.Field(i).Interface()) {
+			continue
+		}
+		key := field.Tag.Get("mapstructure")
+		if field.Tag.Get("reload") != "true" {
+			restart = append(restart, key)
+			continue
+		}
+		current.Field(i).Set(target.Field(i))
+		changed = append(changed, key)
+	}
+	s.cfg = updated
+	subscribers := s.subscribers
+	s.mu.Unlock()
+
+	if len(restart) > 0 {
+		s.app.Log.Warnf("Изменения параметров %v вступят в силу после перезапуска", restart)
+	}
+	if len(changed) == 0 {
+		return
+	}
+
+	s.app.Log.Infof("Применены новые значения параметров %v", changed)
+	event := ChangeEvent{Old: old, New: updated, Changed: changed}
+	for _, fn := range subscribers {
+		fn(event)
+	}
+}
+
+// Redacted возвращает действующую конфигурацию по именам параметров со скрытыми секретами.
+func (s *Store) Redacted() map[string]interface{} {
+	cfg := reflect.ValueOf(s.Get())
+	result := make(map[string]interface{}, cfg.NumField())
+	for i := 0; i < cfg.NumField(); i++ {
+		field := cfg.Type().Field(i)
+		value := cfg.Field(i).Interface()
+		if field.Tag.Get("secret") == "true" && !cfg.Field(i).IsZero() {
+			value = redactedValue
+		}
+		result[field.Tag.Get("mapstructure")] = value
+	}
+	return result
+}
+
+// ReloadableKeys возвращает имена параметров, применяемых без перезапуска.
+func ReloadableKeys() []string {
+	t := reflect.TypeOf(Config{})
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("reload") == "true" {
+			keys = append(keys, t.Field(i).Tag.Get("mapstructure"))
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseTopicMap разбирает параметр name вида "<topic>=<value>;<topic>=<value>" и передает parse значение
+// каждого топика. Топик должен входить в MESSAGE_TYPES и встречаться один раз, format описывает значение
+// в сообщении об ошибке.
+func (c Config) parseTopicMap(name, param, format string, parse func(topic, value string) error) error {
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(param, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		topic, value, ok := strings.Cut(entry, "=")
+		topic = strings.TrimSpace(topic)
+		if !ok || topic == "" {
+			return fmt.Errorf("%s: %q must be <topic>=%s", name, entry, format)
+		}
+		if seen[topic] {
+			return fmt.Errorf("%s: topic %q is listed twice", name, topic)
+		}
+		seen[topic] = true
+		if !c.hasMessageType(topic) {
+			return fmt.Errorf("%s: topic %q is not listed in MESSAGE_TYPES", name, topic)
+		}
+		if err := parse(topic, strings.TrimSpace(value)); err != nil {
+			return fmt.Errorf("%s: topic %q: %w", name, topic, err)
+		}
+	}
+	return nil
+}
+
+func (c Config) hasMessageType(topic string) bool {
+	for _, messageType := range c.MessageTypes {
+		if messageType == topic {
+			return true
+		}
+	}
+	return false
+}
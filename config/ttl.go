@@ -0,0 +1,27 @@
+package config
+
+import (
+	"errors"
+	"time"
+)
+
+// TopicTTLs разбирает MESSAGE_TTL вида "ping=5m;message=24h" в срок жизни сообщений каждого топика.
+// Топики, которых нет в списке, хранят сообщения без ограничения срока.
+func (c Config) TopicTTLs() (map[string]time.Duration, error) {
+	ttls := make(map[string]time.Duration)
+	err := c.parseTopicMap("MESSAGE_TTL", c.MessageTTL, "<duration>", func(topic, value string) error {
+		ttl, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		if ttl <= 0 {
+			return errors.New("ttl must be positive")
+		}
+		ttls[topic] = ttl
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ttls, nil
+}
@@ -0,0 +1,164 @@
+package config
+
+import (
+	"ProjectMessageService/internal/loggers"
+	"ProjectMessageService/internal/tracing"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aead/chacha20poly1305"
+)
+
+var messageTypePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,62}$`)
+
+// ValidMessageType сообщает, можно ли использовать имя как тип сообщений: оно становится именем таблицы
+// и частью имени топика Kafka.
+func ValidMessageType(name string) bool {
+	return messageTypePattern.MatchString(name)
+}
+
+// ValidationError содержит все найденные ошибки конфигурации.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Validate проверяет конфигурацию целиком и возвращает *ValidationError со всеми ошибками.
+func (c Config) Validate() error {
+	var problems []string
+	addf := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	required := []struct{ key, value string }{
+		{"DB_HOST", c.DBHost},
+		{"DB_USER", c.DBUser},
+		{"DB_NAME", c.DBName},
+	}
+	for _, field := range required {
+		if field.value == "" {
+			addf("%s is required", field.key)
+		}
+	}
+	if port, err := strconv.Atoi(c.DBPort); err != nil || port <= 0 || port > 65535 {
+		addf("DB_PORT must be a port number, got %q", c.DBPort)
+	}
+
+	if len(c.KafkaBrokers) == 0 {
+		addf("KAFKA_BROKERS is required")
+	}
+	for _, broker := range c.KafkaBrokers {
+		if _, _, err := net.SplitHostPort(broker); err != nil {
+			addf("KAFKA_BROKERS: broker %q must be host:port", broker)
+		}
+	}
+
+	if len(c.MessageTypes) == 0 {
+		addf("MESSAGE_TYPES is required")
+	}
+	seen := make(map[string]bool)
+	for _, messageType := range c.MessageTypes {
+		if !ValidMessageType(messageType) {
+			addf("MESSAGE_TYPES: %q must match %s", messageType, messageTypePattern)
+		}
+		if seen[messageType] {
+			addf("MESSAGE_TYPES: %q is listed twice", messageType)
+		}
+		seen[messageType] = true
+	}
+
+	if len(c.TokenSymmetricKey) != chacha20poly1305.KeySize {
+		addf("TOKEN_SYMMETRIC_KEY must be exactly %d characters, got %d", chacha20poly1305.KeySize, len(c.TokenSymmetricKey))
+	}
+	if c.AccessTokenDuration <= 0 {
+		addf("ACCESS_TOKEN_DURATION must be positive")
+	}
+	if c.RefreshTokenDuration < c.AccessTokenDuration {
+		addf("REFRESH_TOKEN_DURATION must not be shorter than ACCESS_TOKEN_DURATION")
+	}
+
+	if c.RateLimitRPS < 0 {
+		addf("RATE_LIMIT_RPS must not be negative")
+	}
+	if c.RateLimitRPS > 0 && c.RateLimitBurst < 1 {
+		addf("RATE_LIMIT_BURST must be at least 1 when RATE_LIMIT_RPS is set")
+	}
+
+	switch c.TracingExporter {
+	case "", tracing.ExporterNone, tracing.ExporterOTLP, tracing.ExporterStdout:
+	default:
+		addf("TRACING_EXPORTER must be one of %s, %s, %s, got %q", tracing.ExporterNone, tracing.ExporterOTLP, tracing.ExporterStdout, c.TracingExporter)
+	}
+
+	if c.LogFormat != loggers.FormatText && c.LogFormat != loggers.FormatJSON {
+		addf("LOG_FORMAT must be %s or %s, got %q", loggers.FormatText, loggers.FormatJSON, c.LogFormat)
+	}
+	if !c.LogConsole && c.LogFile == "" {
+		addf("LOG_FILE is required when LOG_CONSOLE is false")
+	}
+	if c.ShutdownTimeout <= 0 {
+		addf("SHUTDOWN_TIMEOUT must be positive")
+	}
+
+	for _, timeout := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"HTTP_READ_TIMEOUT", c.HTTPReadTimeout},
+		{"HTTP_READ_HEADER_TIMEOUT", c.HTTPReadHeaderTimeout},
+		{"HTTP_WRITE_TIMEOUT", c.HTTPWriteTimeout},
+		{"HTTP_IDLE_TIMEOUT", c.HTTPIdleTimeout},
+	} {
+		if timeout.value < 0 {
+			addf("%s must not be negative", timeout.key)
+		}
+	}
+	if c.HTTPMaxBodyBytes <= 0 || c.HTTPMaxBatchBodyBytes <= 0 {
+		addf("HTTP_MAX_BODY_BYTES and HTTP_MAX_BATCH_BODY_BYTES must be positive")
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		addf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	switch c.TLSClientAuth {
+	case "", TLSClientAuthNone:
+	case TLSClientAuthOptional, TLSClientAuthRequire:
+		if c.TLSCertFile == "" || c.TLSClientCAFile == "" {
+			addf("TLS_CLIENT_AUTH=%s requires TLS_CERT_FILE, TLS_KEY_FILE and TLS_CLIENT_CA_FILE", c.TLSClientAuth)
+		}
+	default:
+		addf("TLS_CLIENT_AUTH must be one of %s, %s, %s, got %q", TLSClientAuthNone, TLSClientAuthOptional, TLSClientAuthRequire, c.TLSClientAuth)
+	}
+
+	for _, parse := range []func() error{
+		func() error { _, err := c.ProcessorPipelines(); return err },
+		func() error { _, err := c.TopicTTLs(); return err },
+		func() error { _, err := c.RetentionPolicies(); return err },
+		func() error { _, err := c.PriorityWeights(); return err },
+		func() error { _, err := c.ConsumerWorkers(); return err },
+	} {
+		if err := parse(); err != nil {
+			addf("%v", err)
+		}
+	}
+	if c.MessageTTLPurgeDelay < 0 {
+		addf("MESSAGE_TTL_PURGE_DELAY must not be negative")
+	}
+	if c.RetentionInterval <= 0 {
+		addf("RETENTION_INTERVAL must be positive")
+	}
+	if c.RetentionBatchSize < 1 {
+		addf("RETENTION_BATCH_SIZE must be at least 1")
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func validConfig() Config {
+	return Config{
+		DBHost:                "localhost",
+		DBPort:                "5432",
+		DBUser:                "postgres",
+		DBName:                "postgres",
+		KafkaBrokers:          []string{"localhost:29092", "kafka:9092"},
+		MessageTypes:          []string{"message", "ping"},
+		TokenSymmetricKey:     "12345678901234567890123456789012",
+		AccessTokenDuration:   15 * time.Minute,
+		RefreshTokenDuration:  24 * time.Hour,
+		LogConsole:            true,
+		LogFormat:             "text",
+		ShutdownTimeout:       30 * time.Second,
+		HTTPMaxBodyBytes:      1 << 20,
+		HTTPMaxBatchBodyBytes: 16 << 20,
+		RetentionInterval:     10 * time.Minute,
+		RetentionBatchSize:    1000,
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	require.NoError(t, validConfig().Validate())
+
+	cfg := validConfig()
+	cfg.DBPort = "postgres"
+	cfg.KafkaBrokers = nil
+	cfg.TokenSymmetricKey = "short"
+	cfg.MessageTypes = []string{"ping", "drop table"}
+
+	var validationErr *ValidationError
+	require.True(t, errors.As(cfg.Validate(), &validationErr))
+	require.Len(t, validationErr.Problems, 4)
+}
+
+func TestTopicParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		set     func(cfg *Config, value string)
+		parse   func(cfg Config) (interface{}, error)
+		value   string
+		want    interface{}
+		invalid []string
+	}{
+		{
+			name:    "PROCESSORS",
+			set:     func(cfg *Config, value string) { cfg.Processors = value },
+			parse:   func(cfg Config) (interface{}, error) { return cfg.ProcessorPipelines() },
+			value:   "message = validate, trim ;ping=enrich;",
+			want:    map[string][]string{"message": {"validate", "trim"}, "ping": {"enrich"}},
+			invalid: []string{"orders=enrich", "message", "ping=enrich;ping=trim"},
+		},
+		{
+			name:    "MESSAGE_TTL",
+			set:     func(cfg *Config, value string) { cfg.MessageTTL = value },
+			parse:   func(cfg Config) (interface{}, error) { return cfg.TopicTTLs() },
+			value:   "ping = 5m;message=24h;",
+			want:    map[string]time.Duration{"ping": 5 * time.Minute, "message": 24 * time.Hour},
+			invalid: []string{"orders=1h", "ping", "ping=soon", "ping=-1m", "ping=1m;ping=2m"},
+		},
+		{
+			name:  "RETENTION",
+			set:   func(cfg *Config, value string) { cfg.Retention = value },
+			parse: func(cfg Config) (interface{}, error) { return cfg.RetentionPolicies() },
+			value: "message = max_age:720h, max_rows:1000, keep_unprocessed;ping=max_rows:10,keep_unprocessed:false",
+			want: map[string]RetentionPolicy{
+				"message": {MaxAge: 720 * time.Hour, MaxRows: 1000, KeepUnprocessed: true},
+				"ping":    {MaxRows: 10},
+			},
+			invalid: []string{"orders=max_rows:10", "ping", "ping=keep_unprocessed", "ping=max_age:-1h", "ping=max_rows:many", "ping=max_size:1"},
+		},
+		{
+			name:  "PRIORITIES",
+			set:   func(cfg *Config, value string) { cfg.Priorities = value },
+			parse: func(cfg Config) (interface{}, error) { return cfg.PriorityWeights() },
+			value: "message = high:10, low:2;ping=",
+			want: map[string]map[string]int{
+				"message": {"high": 10, "normal": 3, "low": 2},
+				"ping":    {"high": 6, "normal": 3, "low": 1},
+			},
+			invalid: []string{"orders=", "message", "message=urgent:5", "message=high:0", "message=low:x", "ping=;ping="},
+		},
+		{
+			name:    "CONSUMER_WORKERS",
+			set:     func(cfg *Config, value string) { cfg.Workers = value },
+			parse:   func(cfg Config) (interface{}, error) { return cfg.ConsumerWorkers() },
+			value:   "message = 8;ping=1;",
+			want:    map[string]int{"message": 8, "ping": 1},
+			invalid: []string{"orders=2", "message", "message=0", "message=1000", "message=many", "ping=1;ping=2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.set(&cfg, tt.value)
+			got, err := tt.parse(cfg)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+			require.NoError(t, cfg.Validate())
+
+			for _, value := range tt.invalid {
+				tt.set(&cfg, value)
+				_, err = tt.parse(cfg)
+				require.ErrorContains(t, err, tt.name, value)
+				require.Error(t, cfg.Validate(), value)
+			}
+		})
+	}
+}
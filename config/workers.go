@@ -0,0 +1,27 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// MaxConsumerWorkers ограничивает число обработчиков консьюмера одного топика.
+const MaxConsumerWorkers = 256
+
+// ConsumerWorkers разбирает CONSUMER_WORKERS вида "message=8;ping=2" в число обработчиков консьюмера
+// каждого топика. Консьюмеры топиков, которых нет в списке, обрабатывают сообщения по одному.
+func (c Config) ConsumerWorkers() (map[string]int, error) {
+	workers := make(map[string]int)
+	err := c.parseTopicMap("CONSUMER_WORKERS", c.Workers, "<workers>", func(topic, value string) error {
+		count, err := strconv.Atoi(value)
+		if err != nil || count < 1 || count > MaxConsumerWorkers {
+			return fmt.Errorf("workers must be between 1 and %d, got %q", MaxConsumerWorkers, value)
+		}
+		workers[topic] = count
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return workers, nil
+}
@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"ProjectMessageService/config"
+	"ProjectMessageService/internal/repository"
+	"ProjectMessageService/internal/service"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultReprocessLimit - сколько сообщений отправляется на повторную обработку за запрос, если limit не указан.
+const defaultReprocessLimit = 10000
+
+// GetConfig возвращает действующую конфигурацию со скрытыми секретами
+// и список параметров, которые применяются без перезапуска.
+func (h *Handler) GetConfig(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{
+		"config":     h.settings.Redacted(),
+		"reloadable": config.ReloadableKeys(),
+	})
+}
+
+// GetConsumerLag возвращает смещения и отставание групп консьюмеров всех типов сообщений
+// по разделам Kafka и число необработанных строк в базе.
+func (h *Handler) GetConsumerLag(ctx *gin.Context) {
+	h.consumerLag(ctx, config.MessageTypes())
+}
+
+// GetTopicConsumerLag возвращает отставание консьюмера одного топика.
+func (h *Handler) GetTopicConsumerLag(ctx *gin.Context) {
+	topic, ok := topicParam(ctx)
+	if !ok {
+		return
+	}
+	h.consumerLag(ctx, []string{topic})
+}
+
+func (h *Handler) consumerLag(ctx *gin.Context, messageTypes []string) {
+	lags, err := h.service.ConsumerLags(ctx, messageTypes)
+	if err != nil {
+		ctx.JSON(http.StatusBadGateway, ErrorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"consumers": lags})
+}
+
+type resetOffsetsRequest struct {
+	Position  string     `json:"position" binding:"omitempty,oneof=earliest latest"`
+	Offset    *int64     `json:"offset" binding:"omitempty,min=0"`
+	Timestamp *time.Time `json:"timestamp"`
+	Partition *int       `json:"partition" binding:"omitempty,min=0"`
+	Reprocess bool       `json:"reprocess"`
+	Priority  string     `json:"priority" binding:"omitempty,oneof=high normal low"`
+}
+
+// ResetOffsets перемещает смещения группы консьюмера топика к earliest/latest, смещению или времени.
+// Консьюмеры топика должны быть остановлены, иначе 409.
+func (h *Handler) ResetOffsets(ctx *gin.Context) {
+	topic, ok := topicParam(ctx)
+	if !ok {
+		return
+	}
+
+	var req resetOffsetsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse(err))
+		return
+	}
+	params := service.ResetOffsetsParams{
+		MessageType: topic,
+		Position:    req.Position,
+		Offset:      req.Offset,
+		Timestamp:   req.Timestamp,
+		Partition:   req.Partition,
+		Reprocess:   req.Reprocess,
+		Priority:    req.Priority,
+	}
+	if err := params.Validate(); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse(err))
+		return
+	}
+
+	result, err := h.service.ResetOffsets(ctx, params)
+	if err != nil {
+		status := http.StatusBadGateway
+		switch {
+		case errors.Is(err, service.ErrConsumerGroupActive):
+			status = http.StatusConflict
+		case errors.Is(err, service.ErrNoPriorityTopics):
+			status = http.StatusBadRequest
+		}
+		ctx.JSON(status, ErrorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// reprocessRequest - фильтр повторной обработки. Без from или to интервал не ограничен с этой стороны.
+type reprocessRequest struct {
+	From            time.Time `json:"from"`
+	To              time.Time `json:"to"`
+	AfterID         int       `json:"after_id" binding:"min=0"`
+	OnlyUnprocessed bool      `json:"only_unprocessed"`
+	Limit           int       `json:"limit" binding:"omitempty,min=1,max=100000"`
+	DryRun          bool      `json:"dry_run"`
+}
+
+// Reprocess отправляет сохраненные сообщения топика, подходящие под фильтр, на повторную обработку
+// фоновой задачей и отвечает 202 с задачей. dry_run синхронно возвращает число подходящих сообщений.
+func (h *Handler) Reprocess(ctx *gin.Context) {
+	topic, ok := topicParam(ctx)
+	if !ok {
+		return
+	}
+
+	var req reprocessRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse(err))
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = defaultReprocessLimit
+	}
+
+	params := service.ReprocessParams{
+		Filter: repository.ListMessagesParams{
+			Topic:           topic,
+			AfterID:         req.AfterID,
+			From:            req.From,
+			To:              req.To,
+			OnlyUnprocessed: req.OnlyUnprocessed,
+		},
+		Limit:  req.Limit,
+		DryRun: req.DryRun,
+	}
+	if err := params.Filter.ValidateWindow(); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse(err))
+		return
+	}
+	if !req.DryRun {
+		ctx.JSON(http.StatusAccepted, h.service.StartReprocess(params))
+		return
+	}
+
+	count, err := h.service.ReprocessMessages(ctx, params)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"matched": count})
+}
+
+// GetReprocessJob возвращает состояние задачи повторной обработки, запущенной Reprocess.
+func (h *Handler) GetReprocessJob(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse(err))
+		return
+	}
+	job, ok := h.service.ReprocessJob(id)
+	if !ok {
+		ctx.JSON(http.StatusNotFound, ErrorResponse(fmt.Errorf("reprocess job %d not found", id)))
+		return
+	}
+	ctx.JSON(http.StatusOK, job)
+}
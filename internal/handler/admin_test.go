@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"ProjectMessageService/config"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReprocessWindow(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	previous := config.MessageTypes()
+	config.SetMessageTypes([]string{"message"})
+	t.Cleanup(func() { config.SetMessageTypes(previous) })
+
+	h := &Handler{}
+	for _, body := range []string{
+		`{"from": "2024-05-02T00:00:00Z", "to": "2024-05-01T00:00:00Z"}`,
+		`{"from": "2024-05-01T00:00:00Z", "to": "2024-05-01T00:00:00Z"}`,
+	} {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Params = gin.Params{{Key: "topic", Value: "message"}}
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/admin/topics/message/reprocess", strings.NewReader(body))
+		h.Reprocess(ctx)
+		require.Equal(t, http.StatusBadRequest, w.Code, body)
+		require.Contains(t, w.Body.String(), "to must be after from")
+	}
+}
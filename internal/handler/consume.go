@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"ProjectMessageService/internal/repository"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultConsumeMaxMessages  = 10
+	defaultConsumeWaitSeconds  = 20
+	defaultConsumeLeaseSeconds = 30
+)
+
+type consumeRequest struct {
+	Consumer     string `json:"consumer" binding:"required"`
+	MaxMessages  int    `json:"max_messages" binding:"omitempty,min=1,max=100"`
+	WaitSeconds  *int   `json:"wait_seconds" binding:"omitempty,min=0,max=60"` // 0 - не ждать, без значения - defaultConsumeWaitSeconds
+	LeaseSeconds int    `json:"lease_seconds" binding:"omitempty,min=1,max=3600"`
+}
+
+// wait возвращает время ожидания сообщений: wait_seconds = 0 означает запрос без ожидания.
+func (r consumeRequest) wait() time.Duration {
+	if r.WaitSeconds == nil {
+		return defaultConsumeWaitSeconds * time.Second
+	}
+	return time.Duration(*r.WaitSeconds) * time.Second
+}
+
+type consumeResponse struct {
+	Messages []repository.LeasedMessage `json:"messages"`
+}
+
+type leaseRequest struct {
+	Consumer string   `json:"consumer" binding:"required"`
+	LeaseIDs []string `json:"lease_ids" binding:"required,min=1,dive,uuid"`
+}
+
+// Consume выдает неподтвержденные сообщения топика именованному консьюмеру,
+// ожидая их появления не дольше wait_seconds.
+func (h *Handler) Consume(ctx *gin.Context) {
+	topic, ok := topicParam(ctx)
+	if !ok {
+		return
+	}
+
+	var req consumeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse(err))
+		return
+	}
+	if req.MaxMessages == 0 {
+		req.MaxMessages = defaultConsumeMaxMessages
+	}
+	if req.LeaseSeconds == 0 {
+		req.LeaseSeconds = defaultConsumeLeaseSeconds
+	}
+
+	messages, err := h.service.ConsumeBatch(ctx.Request.Context(), topic, req.Consumer, req.MaxMessages,
+		req.wait(), time.Duration(req.LeaseSeconds)*time.Second)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse(err))
+		return
+	}
+	if messages == nil {
+		messages = []repository.LeasedMessage{}
+	}
+
+	ctx.JSON(http.StatusOK, consumeResponse{Messages: messages})
+}
+
+// Ack подтверждает обработку выданных сообщений.
+func (h *Handler) Ack(ctx *gin.Context) {
+	topic, ok := topicParam(ctx)
+	if !ok {
+		return
+	}
+
+	var req leaseRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse(err))
+		return
+	}
+
+	count, err := h.service.AckMessages(ctx, topic, req.Consumer, req.LeaseIDs)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"acknowledged": count})
+}
+
+// Nack возвращает выданные сообщения в очередь консьюмера.
+func (h *Handler) Nack(ctx *gin.Context) {
+	topic, ok := topicParam(ctx)
+	if !ok {
+		return
+	}
+
+	var req leaseRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse(err))
+		return
+	}
+
+	count, err := h.service.NackMessages(ctx, topic, req.Consumer, req.LeaseIDs)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"released": count})
+}
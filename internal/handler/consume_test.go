@@ -0,0 +1,21 @@
+package handler
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsumeRequestWait(t *testing.T) {
+	for body, want := range map[string]time.Duration{
+		`{"consumer":"c"}`:                  defaultConsumeWaitSeconds * time.Second,
+		`{"consumer":"c","wait_seconds":0}`: 0,
+		`{"consumer":"c","wait_seconds":5}`: 5 * time.Second,
+	} {
+		var req consumeRequest
+		require.NoError(t, json.Unmarshal([]byte(body), &req))
+		require.Equal(t, want, req.wait(), body)
+	}
+}
@@ -8,9 +8,11 @@ import (
 	`ProjectMessageService/internal/token`
 	"ProjectMessageService/internal/utils"
 	`ProjectMessageService/util`
-	"context"
 	`errors`
+	"fmt"
 	"net/http"
+	"strings"
+	`time`
 
 	"github.com/gin-gonic/gin"
 	`github.com/gin-gonic/gin/binding`
@@ -18,15 +20,15 @@ import (
 )
 
 type Handler struct {
-	config     config.Config
+	settings   *config.Store
 	service    *service.MessageService
 	TokenMaker token.Maker
 	app        *config.Application
 	repo       *repository.Repository
 }
 
-func NewHandler(config config.Config, service *service.MessageService, repo *repository.Repository, app *config.Application) *Handler {
-	tokenMaker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
+func NewHandler(settings *config.Store, service *service.MessageService, repo *repository.Repository, app *config.Application) *Handler {
+	tokenMaker, err := token.NewPasetoMaker(settings.Get().TokenSymmetricKey)
 	if err != nil {
 		app.Log.Errorf("cannot create token maker: %v", err)
 		return nil
@@ -36,18 +38,48 @@ func NewHandler(config config.Config, service *service.MessageService, repo *rep
 		_ = v.RegisterValidation("topic", validCurrency)
 	}
 
-	return &Handler{config: config, service: service, TokenMaker: tokenMaker, repo: repo, app: app}
+	return &Handler{settings: settings, service: service, TokenMaker: tokenMaker, repo: repo, app: app}
 }
 
 func (h *Handler) CreateMessage(c *gin.Context) {
 	var input utils.Message
 
 	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(bindStatus(err), ErrorResponse(err))
+		return
+	}
+
+	if err := h.service.ValidateMessage(c, input); err != nil {
+		var validationErr *service.SchemaValidationError
+		if errors.As(err, &validationErr) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": validationErr.Error(), "validation": validationErr})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse(err))
+		return
+	}
+
+	now := time.Now()
+	deliverAt, scheduled, err := input.ScheduledAt(now)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, ErrorResponse(err))
 		return
 	}
+	if err := input.ValidateExpiry(now, deliverAt); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(err))
+		return
+	}
+	if scheduled {
+		message, err := h.service.ScheduleMessage(c.Request.Context(), input, deliverAt, authorizedOwner(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse(err))
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{"status": "message scheduled", "id": message.ID, "deliver_at": message.DeliverAt})
+		return
+	}
 
-	if err := h.service.SaveMessage(context.Background(), input); err != nil {
+	if err := h.service.SaveMessage(c.Request.Context(), input.Immediate()); err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse(err))
 		return
 	}
@@ -55,21 +87,70 @@ func (h *Handler) CreateMessage(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "message received"})
 }
 
-func (h *Handler) GetStats(c *gin.Context) {
-	var topic utils.Messages
+// CreateMessageBatch принимает пакет сообщений в разные топики. Некорректные сообщения
+// отклоняются по отдельности, остальные, в том числе отложенные, сохраняются одной транзакцией.
+func (h *Handler) CreateMessageBatch(c *gin.Context) {
+	var input utils.MessageBatch
 
-	if err := c.ShouldBindJSON(&topic); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse(err))
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(bindStatus(err), ErrorResponse(err))
 		return
 	}
 
-	count, err := h.service.GetStats(context.Background(), topic)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse(err))
-		return
+	results := make([]utils.MessageResult, len(input.Messages))
+	valid := make([]utils.Message, 0, len(input.Messages))
+	validIdx := make([]int, 0, len(input.Messages))
+	scheduled := make([]repository.CreateScheduledMessageParams, 0)
+	scheduledIdx := make([]int, 0)
+	for i, message := range input.Messages {
+		if err := binding.Validator.ValidateStruct(message); err != nil {
+			results[i] = utils.MessageResult{Index: i, Topic: message.Topic, Status: utils.BatchStatusInvalid, Error: err.Error()}
+			continue
+		}
+		if err := h.service.ValidateMessage(c, message); err != nil {
+			var validationErr *service.SchemaValidationError
+			if !errors.As(err, &validationErr) {
+				c.JSON(http.StatusInternalServerError, ErrorResponse(err))
+				return
+			}
+			results[i] = utils.MessageResult{Index: i, Topic: message.Topic, Status: utils.BatchStatusInvalid, Error: err.Error()}
+			continue
+		}
+		now := time.Now()
+		deliverAt, isScheduled, err := message.ScheduledAt(now)
+		if err != nil {
+			results[i] = utils.MessageResult{Index: i, Topic: message.Topic, Status: utils.BatchStatusInvalid, Error: err.Error()}
+			continue
+		}
+		if err := message.ValidateExpiry(now, deliverAt); err != nil {
+			results[i] = utils.MessageResult{Index: i, Topic: message.Topic, Status: utils.BatchStatusInvalid, Error: err.Error()}
+			continue
+		}
+		if isScheduled {
+			scheduled = append(scheduled, repository.CreateScheduledMessageParams{Message: message, DeliverAt: deliverAt, CreatedBy: authorizedOwner(c)})
+			scheduledIdx = append(scheduledIdx, i)
+			continue
+		}
+		valid = append(valid, message.Immediate())
+		validIdx = append(validIdx, i)
+	}
+
+	if len(valid) > 0 || len(scheduled) > 0 {
+		saved, savedScheduled, err := h.service.SaveMessageBatch(c.Request.Context(), valid, scheduled)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse(err))
+			return
+		}
+		for j, result := range saved {
+			result.Index = validIdx[j]
+			results[validIdx[j]] = result
+		}
+		for j, item := range savedScheduled {
+			results[scheduledIdx[j]] = utils.MessageResult{Index: scheduledIdx[j], Topic: item.Topic, ID: int(item.ID), Status: utils.BatchStatusScheduled}
+		}
 	}
 
-	c.JSON(http.StatusOK, gin.H{"processed_messages": count})
+	c.JSON(http.StatusOK, gin.H{"results": results})
 }
 
 func (h *Handler) CreateUser(ctx *gin.Context) {
@@ -78,6 +159,10 @@ func (h *Handler) CreateUser(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, ErrorResponse(err))
 		return
 	}
+	if strings.HasPrefix(req.Username, util.CertOwnerPrefix) {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse(fmt.Errorf("username must not start with %s", util.CertOwnerPrefix)))
+		return
+	}
 
 	hashedPassword, err := util.HashPassword(req.Password)
 	if err != nil {
@@ -110,6 +195,25 @@ func ErrorResponse(err error) gin.H {
 	return gin.H{"error": err.Error()}
 }
 
+// bindStatus возвращает код ответа для ошибки разбора тела запроса.
+func bindStatus(err error) int {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// topicParam извлекает топик из пути запроса и отвечает 404, если такого топика нет.
+func topicParam(ctx *gin.Context) (string, bool) {
+	topic := ctx.Param("topic")
+	if !util.IsSupportedCurrency(topic) {
+		ctx.JSON(http.StatusNotFound, ErrorResponse(fmt.Errorf("unknown topic %s", topic)))
+		return "", false
+	}
+	return topic, true
+}
+
 func (h *Handler) LoginUser(ctx *gin.Context) {
 	var req api.LoginUserRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -137,7 +241,7 @@ func (h *Handler) LoginUser(ctx *gin.Context) {
 	accessToken, accessPayload, err := h.TokenMaker.CreateToken(
 		user.Username,
 		user.Role,
-		h.config.AccessTokenDuration,
+		h.settings.Get().AccessTokenDuration,
 	)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse(err))
@@ -147,7 +251,7 @@ func (h *Handler) LoginUser(ctx *gin.Context) {
 	refreshToken, refreshPayload, err := h.TokenMaker.CreateToken(
 		user.Username,
 		user.Role,
-		h.config.RefreshTokenDuration,
+		h.settings.Get().RefreshTokenDuration,
 	)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse(err))
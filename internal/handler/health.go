@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"ProjectMessageService/internal/service"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readinessTimeout ограничивает время проверок зависимостей в /readyz и /status.
+const readinessTimeout = 2 * time.Second
+
+var startedAt = time.Now()
+
+type readinessResponse struct {
+	Ready  bool                  `json:"ready"`
+	Checks []service.HealthCheck `json:"checks"`
+}
+
+type statusResponse struct {
+	readinessResponse
+	StartedAt time.Time                `json:"started_at"`
+	Uptime    string                   `json:"uptime"`
+	Consumers []service.ConsumerStatus `json:"consumers"`
+}
+
+// Healthz отвечает, пока процесс жив и обслуживает HTTP.
+func (h *Handler) Healthz(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readyz проверяет Postgres, брокер Kafka и консьюмеры топиков, 503 - если сервис не готов.
+func (h *Handler) Readyz(ctx *gin.Context) {
+	response := h.readiness(ctx)
+
+	status := http.StatusOK
+	if !response.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	ctx.JSON(status, response)
+}
+
+// Status возвращает подробное состояние сервиса для администраторов.
+func (h *Handler) Status(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, statusResponse{
+		readinessResponse: h.readiness(ctx),
+		StartedAt:         startedAt,
+		Uptime:            time.Since(startedAt).Round(time.Second).String(),
+		Consumers:         h.service.ConsumerStatuses(),
+	})
+}
+
+func (h *Handler) readiness(ctx *gin.Context) readinessResponse {
+	checkCtx, cancel := context.WithTimeout(ctx.Request.Context(), readinessTimeout)
+	defer cancel()
+
+	checks, ready := h.service.Readiness(checkCtx)
+	return readinessResponse{Ready: ready, Checks: checks}
+}
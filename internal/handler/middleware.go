@@ -1,52 +1,187 @@
 package handler
 
 import (
+	"ProjectMessageService/internal/loggers"
+	"ProjectMessageService/internal/metrics"
 	"ProjectMessageService/internal/token"
+	"ProjectMessageService/util"
 	"errors"
 	"fmt"
 	"net/http"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 const (
 	authorizationHeaderKey  = "authorization"
 	authorizationTypeBearer = "bearer"
 	authorizationPayloadKey = "authorization_payload"
+	clientCertKey           = "client_cert"
+	requestIDHeaderKey      = "X-Request-ID"
 )
 
+// requestIDPattern - допустимый идентификатор запроса клиента: он попадает в журналы и заголовки ответа.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// RequestIDMiddleware принимает идентификатор запроса из заголовка X-Request-ID или создает новый,
+// если заголовка нет или он содержит недопустимые символы, возвращает его в ответе и сохраняет в контексте запроса для журналов.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		requestID := ctx.GetHeader(requestIDHeaderKey)
+		if !requestIDPattern.MatchString(requestID) {
+			requestID = uuid.NewString()
+		}
+
+		ctx.Header(requestIDHeaderKey, requestID)
+		ctx.Request = ctx.Request.WithContext(loggers.WithRequestID(ctx.Request.Context(), requestID))
+		ctx.Next()
+	}
+}
+
 func AuthMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		authorizationHeader := ctx.GetHeader(authorizationHeaderKey)
 		if len(authorizationHeader) == 0 {
 			err := errors.New("authorization header is not provided")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse(err))
 			return
 		}
 
 		fields := strings.Fields(authorizationHeader)
 		if len(fields) < 2 {
 			err := errors.New("invalid authorization header format")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse(err))
 			return
 		}
 
 		authorizationType := strings.ToLower(fields[0])
 		if authorizationType != authorizationTypeBearer {
 			err := fmt.Errorf("unsupported authorization type %s", authorizationType)
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse(err))
 			return
 		}
 
 		accessToken := fields[1]
 		payload, err := tokenMaker.VerifyToken(accessToken)
 		if err != nil {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse(err))
 			return
 		}
 
 		ctx.Set(authorizationPayloadKey, payload)
+		ctx.Request = ctx.Request.WithContext(loggers.WithUsername(ctx.Request.Context(), payload.Username))
 		ctx.Next()
 	}
 }
+
+// ClientCertAuthMiddleware аутентифицирует внутренних публикаторов по клиентскому сертификату, проверенному
+// при TLS-рукопожатии (TLS_CLIENT_AUTH). Имя пользователя берется из CommonName сертификата, роль - publisher.
+// Запросы без проверенного сертификата или с заголовком Authorization проходят обычную проверку токена.
+// Сертификат без CommonName отклоняется: запрос нельзя отнести к пользователю.
+func ClientCertAuthMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
+	tokenAuth := AuthMiddleware(tokenMaker)
+	return func(ctx *gin.Context) {
+		state := ctx.Request.TLS
+		if ctx.GetHeader(authorizationHeaderKey) != "" || state == nil || len(state.VerifiedChains) == 0 {
+			tokenAuth(ctx)
+			return
+		}
+
+		cert := state.VerifiedChains[0][0]
+		if cert.Subject.CommonName == "" {
+			err := errors.New("client certificate has no common name")
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse(err))
+			return
+		}
+		payload := &token.Payload{
+			Username:  cert.Subject.CommonName,
+			Role:      util.PublisherRole,
+			IssuedAt:  cert.NotBefore,
+			ExpiredAt: cert.NotAfter,
+		}
+		ctx.Set(authorizationPayloadKey, payload)
+		ctx.Set(clientCertKey, true)
+		ctx.Request = ctx.Request.WithContext(loggers.WithUsername(ctx.Request.Context(), payload.Username))
+		ctx.Next()
+	}
+}
+
+// BodyLimitMiddleware ограничивает размер тела запроса и отвечает 413, если он превышен.
+func BodyLimitMiddleware(maxBytes int64) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if ctx.Request.ContentLength > maxBytes {
+			err := fmt.Errorf("request body exceeds %d bytes", maxBytes)
+			ctx.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, ErrorResponse(err))
+			return
+		}
+		// Тело без Content-Length обрезается при чтении, ошибку обрабатывает bindStatus
+		ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxBytes)
+		ctx.Next()
+	}
+}
+
+// RoleMiddleware пропускает только пользователей с одной из перечисленных ролей.
+// Должен подключаться после AuthMiddleware.
+func RoleMiddleware(roles ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		payload, ok := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		if ok {
+			for _, role := range roles {
+				if payload.Role == role {
+					ctx.Next()
+					return
+				}
+			}
+		}
+
+		err := errors.New("permission denied")
+		ctx.AbortWithStatusJSON(http.StatusForbidden, ErrorResponse(err))
+	}
+}
+
+// MetricsMiddleware учитывает количество и длительность запросов по шаблону маршрута Gin.
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.ObserveHTTPRequest(ctx.Request.Method, route, ctx.Writer.Status(), time.Since(start))
+	}
+}
+
+// authorizedPayload возвращает данные токена пользователя, установленные AuthMiddleware.
+func authorizedPayload(ctx *gin.Context) (*token.Payload, bool) {
+	value, ok := ctx.Get(authorizationPayloadKey)
+	if !ok {
+		return nil, false
+	}
+	payload, ok := value.(*token.Payload)
+	return payload, ok
+}
+
+// authorizedUsername возвращает имя пользователя запроса или пустую строку.
+func authorizedUsername(ctx *gin.Context) string {
+	if payload, ok := authorizedPayload(ctx); ok {
+		return payload.Username
+	}
+	return ""
+}
+
+// authorizedOwner возвращает владельца объектов, создаваемых запросом: имя пользователя токена или
+// cert:<CommonName> для клиентского сертификата, чтобы сертификат не получил доступ к объектам пользователя
+// с тем же именем.
+func authorizedOwner(ctx *gin.Context) string {
+	username := authorizedUsername(ctx)
+	if username != "" && ctx.GetBool(clientCertKey) {
+		return util.CertOwnerPrefix + username
+	}
+	return username
+}
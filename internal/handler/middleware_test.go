@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"ProjectMessageService/internal/token"
+	"ProjectMessageService/util"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestIDMiddleware())
+	r.GET("/", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+
+	for header, keep := range map[string]bool{
+		"":                       false,
+		"req-1.a_B":              true,
+		"bad id":                 false,
+		"bad\r\nX-Injected: 1":   false,
+		"<script>":               false,
+		strings.Repeat("a", 128): true,
+		strings.Repeat("a", 129): false,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(requestIDHeaderKey, header)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		got := w.Header().Get(requestIDHeaderKey)
+		if keep {
+			require.Equal(t, header, got)
+		} else {
+			require.NotEqual(t, header, got)
+			require.Regexp(t, requestIDPattern, got)
+		}
+	}
+}
+
+func TestClientCertAuthMiddleware(t *testing.T) {
+	tokenMaker, err := token.NewPasetoMaker(strings.Repeat("k", 32))
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(ClientCertAuthMiddleware(tokenMaker))
+	r.GET("/", func(ctx *gin.Context) {
+		payload, _ := authorizedPayload(ctx)
+		ctx.String(http.StatusOK, payload.Username+"/"+payload.Role)
+	})
+
+	request := func(commonName string, withTLS bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if withTLS {
+			cert := &x509.Certificate{Subject: pkix.Name{CommonName: commonName}}
+			req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request("billing", true)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "billing/"+util.PublisherRole, w.Body.String())
+
+	require.Equal(t, http.StatusUnauthorized, request("", true).Code)
+	// Без сертификата нужен токен
+	require.Equal(t, http.StatusUnauthorized, request("billing", false).Code)
+}
+
+func TestAuthorizedOwner(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Set(authorizationPayloadKey, &token.Payload{Username: "billing"})
+	require.Equal(t, "billing", authorizedOwner(ctx))
+
+	// Владелец с клиентским сертификатом не совпадает с пользователем токена с тем же именем
+	ctx.Set(clientCertKey, true)
+	require.Equal(t, util.CertOwnerPrefix+"billing", authorizedOwner(ctx))
+}
+
+func TestBodyLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/", BodyLimitMiddleware(16), func(ctx *gin.Context) {
+		var body map[string]string
+		if err := ctx.ShouldBindJSON(&body); err != nil {
+			ctx.JSON(bindStatus(err), ErrorResponse(err))
+			return
+		}
+		ctx.Status(http.StatusOK)
+	})
+
+	send := func(body string, chunked bool) int {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		if chunked {
+			req.ContentLength = -1
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	require.Equal(t, http.StatusOK, send(`{"a":"b"}`, false))
+	require.Equal(t, http.StatusRequestEntityTooLarge, send(`{"a":"0123456789abcdef"}`, false))
+	// Тело без Content-Length обрезается при чтении
+	require.Equal(t, http.StatusRequestEntityTooLarge, send(`{"a":"0123456789abcdef"}`, true))
+	require.Equal(t, http.StatusBadRequest, send(`{"a":`, true))
+}
+
+func TestBindStatus(t *testing.T) {
+	require.Equal(t, http.StatusRequestEntityTooLarge, bindStatus(fmt.Errorf("read body: %w", &http.MaxBytesError{Limit: 1})))
+	require.Equal(t, http.StatusBadRequest, bindStatus(errors.New("invalid json")))
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"ProjectMessageService/config"
+	"ProjectMessageService/internal/token"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// rateLimiterIdle - через сколько неактивный клиент удаляется из таблицы ограничителей.
+const rateLimiterIdle = 3 * time.Minute
+
+type rateLimitClient struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// rateLimiter ограничивает частоту запросов каждого пользователя (или адреса, если пользователь неизвестен).
+type rateLimiter struct {
+	mu          sync.Mutex
+	limit       rate.Limit
+	burst       int
+	clients     map[string]*rateLimitClient
+	lastCleanup time.Time
+}
+
+// configure задает новые лимиты. Накопленные токены клиентов сбрасываются.
+func (l *rateLimiter) configure(rps float64, burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit = rate.Limit(rps)
+	l.burst = burst
+	l.clients = make(map[string]*rateLimitClient)
+}
+
+func (l *rateLimiter) allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.limit <= 0 {
+		return true
+	}
+
+	now := time.Now()
+	if now.Sub(l.lastCleanup) > rateLimiterIdle {
+		for k, client := range l.clients {
+			if now.Sub(client.lastSeen) > rateLimiterIdle {
+				delete(l.clients, k)
+			}
+		}
+		l.lastCleanup = now
+	}
+
+	client, ok := l.clients[key]
+	if !ok {
+		client = &rateLimitClient{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[key] = client
+	}
+	client.lastSeen = now
+	return client.limiter.AllowN(now, 1)
+}
+
+// RateLimitMiddleware ограничивает частоту запросов по RATE_LIMIT_RPS и RATE_LIMIT_BURST.
+// Лимиты обновляются при перезагрузке конфигурации. Подключается после AuthMiddleware.
+func RateLimitMiddleware(settings *config.Store) gin.HandlerFunc {
+	limiter := &rateLimiter{}
+	cfg := settings.Get()
+	limiter.configure(cfg.RateLimitRPS, cfg.RateLimitBurst)
+	settings.Subscribe(func(e config.ChangeEvent) {
+		if e.Has("RATE_LIMIT_RPS", "RATE_LIMIT_BURST") {
+			limiter.configure(e.New.RateLimitRPS, e.New.RateLimitBurst)
+		}
+	})
+
+	return func(ctx *gin.Context) {
+		key := ctx.ClientIP()
+		if payload, ok := ctx.Get(authorizationPayloadKey); ok {
+			key = "user:" + payload.(*token.Payload).Username
+		}
+
+		if !limiter.allow(key) {
+			err := errors.New("rate limit exceeded")
+			ctx.AbortWithStatusJSON(http.StatusTooManyRequests, ErrorResponse(err))
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"ProjectMessageService/internal/repository"
+	"ProjectMessageService/internal/routing"
+	"ProjectMessageService/internal/utils"
+	"ProjectMessageService/util"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v4"
+)
+
+type routingRuleRequest struct {
+	Topic       string              `json:"topic" binding:"required"`
+	Name        string              `json:"name" binding:"max=128"`
+	Priority    int                 `json:"priority"`
+	Conditions  []routing.Condition `json:"conditions"`
+	Action      string              `json:"action" binding:"required"`
+	TargetTopic string              `json:"target_topic"`
+	IsActive    *bool               `json:"is_active"`
+}
+
+// rule проверяет правило и возвращает его; по умолчанию правило активно.
+func (req routingRuleRequest) rule() (routing.Rule, error) {
+	rule := routing.Rule{
+		Topic:       req.Topic,
+		Name:        req.Name,
+		Priority:    req.Priority,
+		Conditions:  req.Conditions,
+		Action:      req.Action,
+		TargetTopic: req.TargetTopic,
+		IsActive:    req.IsActive == nil || *req.IsActive,
+	}
+	if rule.Conditions == nil {
+		rule.Conditions = []routing.Condition{}
+	}
+	if !util.IsSupportedCurrency(rule.Topic) {
+		return rule, fmt.Errorf("unknown topic %s", rule.Topic)
+	}
+	if rule.TargetTopic != "" && !util.IsSupportedCurrency(rule.TargetTopic) {
+		return rule, fmt.Errorf("unknown target_topic %s", rule.TargetTopic)
+	}
+	return rule, rule.Validate()
+}
+
+// CreateRoutingRule добавляет правило маршрутизации сообщений топика.
+func (h *Handler) CreateRoutingRule(ctx *gin.Context) {
+	var req routingRuleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse(err))
+		return
+	}
+	rule, err := req.rule()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse(err))
+		return
+	}
+
+	created, err := h.repo.CreateRoutingRule(ctx, repository.CreateRoutingRuleParams{
+		Topic:       rule.Topic,
+		Name:        rule.Name,
+		Priority:    rule.Priority,
+		Conditions:  rule.Conditions,
+		Action:      rule.Action,
+		TargetTopic: rule.TargetTopic,
+		IsActive:    rule.IsActive,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse(err))
+		return
+	}
+	h.service.InvalidateRoutes()
+	ctx.JSON(http.StatusOK, created)
+}
+
+// ListRoutingRules возвращает правила маршрутизации, параметр topic ограничивает их одним топиком.
+func (h *Handler) ListRoutingRules(ctx *gin.Context) {
+	rules, err := h.repo.ListRoutingRules(ctx, ctx.Query("topic"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+type setRoutingRuleActiveRequest struct {
+	IsActive *bool `json:"is_active" binding:"required"`
+}
+
+// SetRoutingRuleActive включает или отключает правило.
+func (h *Handler) SetRoutingRuleActive(ctx *gin.Context) {
+	id, ok := routingRuleID(ctx)
+	if !ok {
+		return
+	}
+	var req setRoutingRuleActiveRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse(err))
+		return
+	}
+
+	rule, err := h.repo.SetRoutingRuleActive(ctx, id, *req.IsActive)
+	if err != nil {
+		ctx.JSON(routingRuleStatus(err), ErrorResponse(err))
+		return
+	}
+	h.service.InvalidateRoutes()
+	ctx.JSON(http.StatusOK, rule)
+}
+
+// DeleteRoutingRule удаляет правило.
+func (h *Handler) DeleteRoutingRule(ctx *gin.Context) {
+	id, ok := routingRuleID(ctx)
+	if !ok {
+		return
+	}
+	if err := h.repo.DeleteRoutingRule(ctx, id); err != nil {
+		ctx.JSON(routingRuleStatus(err), ErrorResponse(err))
+		return
+	}
+	h.service.InvalidateRoutes()
+	ctx.Status(http.StatusNoContent)
+}
+
+type testRoutingRequest struct {
+	Message utils.Message       `json:"message" binding:"required"`
+	Rule    *routingRuleRequest `json:"rule"`
+}
+
+// TestRouting проверяет образец сообщения без публикации: по правилу rule, если оно передано,
+// иначе по активным правилам топика сообщения.
+func (h *Handler) TestRouting(ctx *gin.Context) {
+	var req testRoutingRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse(err))
+		return
+	}
+
+	if req.Rule != nil {
+		rule, err := req.Rule.rule()
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse(err))
+			return
+		}
+		if rule.Topic != req.Message.Topic {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse(errors.New("rule.topic must match message.topic")))
+			return
+		}
+		rule.IsActive = true
+		result, err := routing.Evaluate([]routing.Rule{rule}, req.Message)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusOK, result)
+		return
+	}
+
+	result, err := h.service.TestRoutes(ctx, req.Message)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+func routingRuleID(ctx *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse(fmt.Errorf("invalid rule id %q", ctx.Param("id"))))
+		return 0, false
+	}
+	return id, true
+}
+
+func routingRuleStatus(err error) int {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
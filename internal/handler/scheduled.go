@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"ProjectMessageService/internal/repository"
+	"ProjectMessageService/util"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v4"
+)
+
+// GetScheduledMessage возвращает отложенное сообщение и время его публикации.
+func (h *Handler) GetScheduledMessage(ctx *gin.Context) {
+	message, ok := h.scheduledMessage(ctx)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, message)
+}
+
+// CancelScheduledMessage отменяет еще не опубликованное отложенное сообщение, 409 - если оно уже опубликовано
+// или публикуется.
+func (h *Handler) CancelScheduledMessage(ctx *gin.Context) {
+	message, ok := h.scheduledMessage(ctx)
+	if !ok {
+		return
+	}
+
+	err := h.repo.CancelScheduledMessage(ctx, message.ID)
+	switch {
+	case errors.Is(err, repository.ErrAlreadyDelivered), errors.Is(err, repository.ErrDeliveryInProgress):
+		ctx.JSON(http.StatusConflict, ErrorResponse(err))
+	case errors.Is(err, pgx.ErrNoRows):
+		ctx.JSON(http.StatusNotFound, ErrorResponse(err))
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse(err))
+	default:
+		ctx.Status(http.StatusNoContent)
+	}
+}
+
+// scheduledMessage находит отложенное сообщение по id из пути. Чужие сообщения доступны только администраторам.
+func (h *Handler) scheduledMessage(ctx *gin.Context) (repository.ScheduledMessage, bool) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse(fmt.Errorf("invalid message id %q", ctx.Param("id"))))
+		return repository.ScheduledMessage{}, false
+	}
+
+	message, err := h.repo.GetScheduledMessage(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, ErrorResponse(fmt.Errorf("scheduled message %d not found", id)))
+			return message, false
+		}
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse(err))
+		return message, false
+	}
+
+	// Публикатор с клиентским сертификатом управляет сообщениями, созданными с тем же CommonName,
+	// но не сообщениями пользователя с таким же именем
+	payload, ok := authorizedPayload(ctx)
+	isAdmin := ok && payload.Role == util.AdminRole
+	if !isAdmin && authorizedOwner(ctx) != message.CreatedBy {
+		ctx.JSON(http.StatusNotFound, ErrorResponse(fmt.Errorf("scheduled message %d not found", id)))
+		return message, false
+	}
+	return message, true
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"ProjectMessageService/internal/service"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v4"
+)
+
+type registerSchemaRequest struct {
+	Schema        json.RawMessage `json:"schema" binding:"required"`
+	Compatibility string          `json:"compatibility" binding:"omitempty,oneof=backward forward full none"`
+}
+
+// RegisterSchema регистрирует новую версию JSON Schema топика. Новая версия проверяется на совместимость
+// с предыдущей в режиме, заданном первой версией схемы топика (по умолчанию backward).
+func (h *Handler) RegisterSchema(ctx *gin.Context) {
+	topic, ok := topicParam(ctx)
+	if !ok {
+		return
+	}
+
+	var req registerSchemaRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse(err))
+		return
+	}
+	schema, err := h.service.RegisterSchema(ctx, topic, req.Schema, req.Compatibility)
+	if err != nil {
+		var compatibilityErr *service.SchemaCompatibilityError
+		if errors.As(err, &compatibilityErr) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": compatibilityErr.Error(), "problems": compatibilityErr.Problems})
+			return
+		}
+		if errors.Is(err, service.ErrCompatibilityChange) || errors.Is(err, service.ErrSchemaVersionConflict) {
+			ctx.JSON(http.StatusConflict, ErrorResponse(err))
+			return
+		}
+		if errors.Is(err, service.ErrInvalidSchema) {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, schema)
+}
+
+// GetSchema возвращает последнюю версию схемы топика.
+func (h *Handler) GetSchema(ctx *gin.Context) {
+	topic, ok := topicParam(ctx)
+	if !ok {
+		return
+	}
+
+	schema, err := h.service.GetSchema(ctx, topic)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, ErrorResponse(fmt.Errorf("topic %s has no schema", topic)))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, schema)
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"ProjectMessageService/config"
+	"ProjectMessageService/internal/repository"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultStatsWindow - окно статистики, если from не указан.
+	defaultStatsWindow = 24 * time.Hour
+	// maxStatsBuckets ограничивает длину временного ряда в ответе.
+	maxStatsBuckets = 1000
+)
+
+type statsQuery struct {
+	From   time.Time `form:"from"`
+	To     time.Time `form:"to"`
+	Bucket string    `form:"bucket" binding:"omitempty,oneof=minute hour day"`
+}
+
+// GetStats возвращает статистику всех типов сообщений и сумму по ним.
+// Параметры запроса: from и to в RFC3339 (по умолчанию последние сутки), bucket - minute, hour (по умолчанию) или day.
+func (h *Handler) GetStats(ctx *gin.Context) {
+	params, ok := bindStatsParams(ctx)
+	if !ok {
+		return
+	}
+
+	stats, err := h.service.GetStats(ctx, params, config.MessageTypes())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, stats)
+}
+
+// GetTopicStats возвращает статистику одного топика с теми же параметрами, что и GetStats.
+func (h *Handler) GetTopicStats(ctx *gin.Context) {
+	topic, ok := topicParam(ctx)
+	if !ok {
+		return
+	}
+	params, ok := bindStatsParams(ctx)
+	if !ok {
+		return
+	}
+	params.Topic = topic
+
+	stats, err := h.service.GetTopicStats(ctx, params)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, stats)
+}
+
+// bindStatsParams разбирает окно статистики и отвечает 400, если оно некорректно.
+func bindStatsParams(ctx *gin.Context) (repository.StatsParams, bool) {
+	var query statsQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse(err))
+		return repository.StatsParams{}, false
+	}
+
+	if query.To.IsZero() {
+		query.To = time.Now()
+	}
+	if query.From.IsZero() {
+		query.From = query.To.Add(-defaultStatsWindow)
+	}
+	if query.Bucket == "" {
+		query.Bucket = repository.BucketHour
+	}
+
+	if !query.From.Before(query.To) {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse(fmt.Errorf("from must be before to")))
+		return repository.StatsParams{}, false
+	}
+	if buckets := query.To.Sub(query.From) / repository.BucketDuration(query.Bucket); buckets > maxStatsBuckets {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse(fmt.Errorf("window contains %d %s buckets, at most %d allowed", buckets, query.Bucket, maxStatsBuckets)))
+		return repository.StatsParams{}, false
+	}
+
+	return repository.StatsParams{From: query.From, To: query.To, Bucket: query.Bucket}, true
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"ProjectMessageService/internal/repository"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBindStatsParams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	bind := func(query string) (repository.StatsParams, bool, int) {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/stats?"+query, nil)
+		params, ok := bindStatsParams(ctx)
+		return params, ok, w.Code
+	}
+
+	params, ok, _ := bind("")
+	require.True(t, ok)
+	require.Equal(t, repository.BucketHour, params.Bucket)
+	require.Equal(t, defaultStatsWindow, params.To.Sub(params.From))
+	require.WithinDuration(t, time.Now(), params.To, time.Second)
+
+	params, ok, _ = bind("from=2024-05-01T00:00:00Z&to=2024-05-01T12:00:00Z&bucket=minute")
+	require.True(t, ok)
+	require.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), params.From.UTC())
+	require.Equal(t, repository.BucketMinute, params.Bucket)
+
+	for _, query := range []string{
+		"bucket=week",
+		"from=yesterday",
+		"from=2024-05-02T00:00:00Z&to=2024-05-01T00:00:00Z",
+		// 1441 минутный интервал больше maxStatsBuckets
+		"from=2024-05-01T00:00:00Z&to=2024-05-02T00:01:00Z&bucket=minute",
+	} {
+		_, ok, code := bind(query)
+		require.False(t, ok, query)
+		require.Equal(t, http.StatusBadRequest, code, query)
+	}
+}
@@ -69,7 +69,7 @@ func (h *Handler) RenewAccessToken(ctx *gin.Context) {
 	accessToken, accessPayload, err := h.TokenMaker.CreateToken(
 		refreshPayload.Username,
 		refreshPayload.Role,
-		h.config.AccessTokenDuration,
+		h.settings.Get().AccessTokenDuration,
 	)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse(err))
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"ProjectMessageService/internal/repository"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type createWebhookRequest struct {
+	URL    string `json:"url" binding:"required,url"`
+	Secret string `json:"secret" binding:"required,min=16"`
+	Filter string `json:"filter"`
+}
+
+// CreateWebhook регистрирует подписку на обработанные сообщения топика.
+// Если задан filter, доставляются только сообщения, содержащие эту подстроку.
+func (h *Handler) CreateWebhook(ctx *gin.Context) {
+	topic, ok := topicParam(ctx)
+	if !ok {
+		return
+	}
+
+	var req createWebhookRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse(err))
+		return
+	}
+
+	webhook, err := h.repo.CreateWebhook(ctx, repository.CreateWebhookParams{
+		Topic:  topic,
+		URL:    req.URL,
+		Secret: req.Secret,
+		Filter: req.Filter,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, webhook)
+}
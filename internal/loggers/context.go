@@ -0,0 +1,38 @@
+package loggers
+
+import "context"
+
+type contextKey string
+
+const (
+	requestIDKey contextKey = "request_id"
+	usernameKey  contextKey = "username"
+)
+
+// WithRequestID возвращает контекст с идентификатором запроса для записей журнала.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestID возвращает идентификатор запроса из контекста.
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
+// WithUsername возвращает контекст с именем аутентифицированного пользователя.
+func WithUsername(ctx context.Context, username string) context.Context {
+	return context.WithValue(ctx, usernameKey, username)
+}
+
+// Username возвращает имя пользователя из контекста.
+func Username(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	username, _ := ctx.Value(usernameKey).(string)
+	return username
+}
@@ -1,22 +1,46 @@
 package loggers
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
+	"sync"
 	"time"
 
 	"github.com/gookit/slog"
 	"github.com/gookit/slog/handler"
+	"github.com/gookit/slog/rotatefile"
 )
 
+// Форматы записей журнала.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Rotation - параметры ротации и хранения файлов журнала.
+type Rotation struct {
+	Interval   time.Duration // Ротация по времени, 0 - отключена
+	MaxSize    uint64        // Ротация по размеру файла в байтах, 0 - отключена
+	Compress   bool          // Сжимать ротированные файлы gzip
+	MaxAge     time.Duration // Удалять ротированные файлы старше, 0 - без ограничения
+	MaxBackups uint          // Хранить не больше ротированных файлов, 0 - без ограничения
+}
+
 // Глобальные переменные с начальными значениями.
 var (
 	logConsole  = true
+	logFormat   = FormatText
+	logFile     = "log/service.log"
+	logRotation = Rotation{Interval: 24 * time.Hour, MaxSize: 100 << 20, Compress: true, MaxAge: 7 * 24 * time.Hour, MaxBackups: 30}
 	IsDebugMode = true
 	IsInfoMode  = true
 	IsWarnMode  = true
+
+	// levelsMu защищает IsDebugMode, IsInfoMode и IsWarnMode, которые можно менять во время работы
+	levelsMu sync.RWMutex
 )
 
 // SetLogConsole устанавливает значение для logConsole.
@@ -24,18 +48,39 @@ func SetLogConsole(value bool) {
 	logConsole = value
 }
 
+// SetLogFormat устанавливает формат записей: FormatText или FormatJSON.
+func SetLogFormat(value string) {
+	logFormat = value
+}
+
+// SetLogFile устанавливает путь к файлу журнала, используемому при выключенном logConsole.
+func SetLogFile(value string) {
+	logFile = value
+}
+
+// SetRotation устанавливает параметры ротации файла журнала.
+func SetRotation(value Rotation) {
+	logRotation = value
+}
+
 // SetIsDebugMode устанавливает значение для IsDebugMode.
 func SetIsDebugMode(value bool) {
+	levelsMu.Lock()
+	defer levelsMu.Unlock()
 	IsDebugMode = value
 }
 
 // SetIsInfoMode устанавливает значение для IsInfoMode.
 func SetIsInfoMode(value bool) {
+	levelsMu.Lock()
+	defer levelsMu.Unlock()
 	IsInfoMode = value
 }
 
 // SetIsWarnMode устанавливает значение для IsWarnMode.
 func SetIsWarnMode(value bool) {
+	levelsMu.Lock()
+	defer levelsMu.Unlock()
 	IsWarnMode = value
 }
 
@@ -48,10 +93,57 @@ func (f *CustomFormatter) Format(record *slog.Record) ([]byte, error) {
 	caller := record.Caller
 	fileName := filepath.Base(caller.File) // Получаем только имя файла
 	funcName := getFunctionName(caller.PC) // Получаем имя функции
-	logMessage := fmt.Sprintf("[%s] [%s] [%s:%d,%s] %s\n", record.Level.String(), record.Time.Format("2006-01-02 15:04:05"), fileName, caller.Line, funcName, record.Message)
+	logMessage := fmt.Sprintf("[%s] [%s] [%s:%d,%s] %s%s\n", record.Level.String(), record.Time.Format("2006-01-02 15:04:05"), fileName, caller.Line, funcName, contextSuffix(record), record.Message)
 	return []byte(logMessage), nil
 }
 
+// contextSuffix возвращает идентификатор запроса и пользователя из контекста записи для текстового формата.
+func contextSuffix(record *slog.Record) string {
+	var suffix string
+	if requestID := RequestID(record.Ctx); requestID != "" {
+		suffix += fmt.Sprintf("[request_id=%s] ", requestID)
+	}
+	if username := Username(record.Ctx); username != "" {
+		suffix += fmt.Sprintf("[user=%s] ", username)
+	}
+	return suffix
+}
+
+// JSONFormatter - формат записи в виде одного JSON-объекта на строку.
+type JSONFormatter struct{}
+
+type jsonRecord struct {
+	Level     string         `json:"level"`
+	Time      string         `json:"time"`
+	Caller    string         `json:"caller"`
+	Func      string         `json:"func"`
+	Message   string         `json:"message"`
+	RequestID string         `json:"request_id,omitempty"`
+	Username  string         `json:"username,omitempty"`
+	Fields    map[string]any `json:"fields,omitempty"`
+}
+
+// Format реализует метод интерфейса slog.Formatter.
+func (f *JSONFormatter) Format(record *slog.Record) ([]byte, error) {
+	caller := record.Caller
+	entry := jsonRecord{
+		Level:     record.Level.String(),
+		Time:      record.Time.Format(time.RFC3339Nano),
+		Caller:    fmt.Sprintf("%s:%d", filepath.Base(caller.File), caller.Line),
+		Func:      getFunctionName(caller.PC),
+		Message:   record.Message,
+		RequestID: RequestID(record.Ctx),
+		Username:  Username(record.Ctx),
+		Fields:    record.Fields,
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
 // getFunctionName возвращает короткое имя функции по Program Counter (PC).
 func getFunctionName(pc uintptr) string {
 	fn := runtime.FuncForPC(pc)
@@ -67,55 +159,78 @@ func getFunctionName(pc uintptr) string {
 func SetupLogger() *slog.Logger {
 	// Создаем логгер
 	logger := slog.New()
+	// По умолчанию slog не завершает процесс после Fatal
+	logger.ExitFunc = os.Exit
 
 	// Устанавливаем форматтер
-	formatter := &CustomFormatter{}
+	var formatter slog.Formatter = &CustomFormatter{}
+	if logFormat == FormatJSON {
+		formatter = &JSONFormatter{}
+	}
 
 	if logConsole {
 		// Создаем хэндлер для вывода в консоль с кастомным форматтером
-		consoleHandler := handler.NewConsoleHandler(getLogLevels())
+		consoleHandler := handler.NewConsoleHandler(slog.AllLevels)
 		consoleHandler.SetFormatter(formatter)
-		logger.AddHandler(consoleHandler)
+		logger.AddHandler(levelSwitch{consoleHandler})
 	} else {
-		// Получаем текущее время и дату
-		now := time.Now()
-
-		// Определим путь к файлу лога с учетом текущей даты в формате DD-MM-YYYY
-		logFilePath := fmt.Sprintf("log/error-%s.log", now.Format("02-01-2006"))
-
 		// Создаем директорию для логов, если её нет
-		err := os.MkdirAll(filepath.Dir(logFilePath), 0755)
+		err := os.MkdirAll(filepath.Dir(logFile), 0755)
 		if err != nil {
 			panic(fmt.Sprintf("Error creating log directory: %v", err))
 		}
 
-		// Открываем файл для записи логов
-		logFile, err := os.OpenFile(logFilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+		// Открываем файл с ротацией по времени и размеру
+		logWriter, err := newRotateWriter(logFile, logRotation)
 		if err != nil {
 			panic(fmt.Sprintf("Error opening log file: %v", err))
 		}
 
 		// Создаем хэндлер для записи в файл с кастомным форматтером
-		fileHandler := handler.NewIOWriterHandler(logFile, getLogLevels())
+		fileHandler := handler.NewSyncCloseHandler(logWriter, slog.AllLevels)
 		fileHandler.SetFormatter(formatter)
-		logger.AddHandler(fileHandler)
+		logger.AddHandler(levelSwitch{fileHandler})
 	}
 
 	return logger
 }
 
-func getLogLevels() []slog.Level {
-	levels := []slog.Level{slog.ErrorLevel, slog.FatalLevel}
-
-	if IsWarnMode {
-		levels = append(levels, slog.WarnLevel)
-	}
-	if IsInfoMode {
-		levels = append(levels, slog.InfoLevel)
-	}
-	if IsDebugMode {
-		levels = append(levels, slog.DebugLevel)
+// newRotateWriter открывает файл журнала, который ротируется по времени и размеру.
+// Ротированные файлы получают суффикс с датой, сжимаются и удаляются по MaxAge и MaxBackups.
+func newRotateWriter(path string, rotation Rotation) (*rotatefile.Writer, error) {
+	cfg := rotatefile.NewConfig(path)
+	cfg.RotateTime = rotatefile.RotateTime(rotation.Interval / time.Second)
+	cfg.MaxSize = rotation.MaxSize
+	cfg.Compress = rotation.Compress
+	cfg.BackupNum = rotation.MaxBackups
+	cfg.BackupTime = uint(rotation.MaxAge / time.Hour)
+	if rotation.MaxAge > 0 && cfg.BackupTime == 0 {
+		cfg.BackupTime = 1 // Минимальный срок хранения - один час
 	}
+	return cfg.Create()
+}
 
-	return levels
+// levelSwitch пропускает в обработчик только уровни, включенные на текущий момент,
+// поэтому SetIsDebugMode и другие переключатели действуют на уже созданный логгер.
+type levelSwitch struct {
+	slog.Handler
+}
+
+func (h levelSwitch) IsHandling(level slog.Level) bool {
+	return isLevelEnabled(level) && h.Handler.IsHandling(level)
+}
+
+func isLevelEnabled(level slog.Level) bool {
+	levelsMu.RLock()
+	defer levelsMu.RUnlock()
+
+	switch level {
+	case slog.WarnLevel, slog.NoticeLevel:
+		return IsWarnMode
+	case slog.InfoLevel:
+		return IsInfoMode
+	case slog.DebugLevel, slog.TraceLevel:
+		return IsDebugMode
+	}
+	return true
 }
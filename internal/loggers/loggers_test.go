@@ -0,0 +1,46 @@
+package loggers
+
+import (
+	"context"
+	"encoding/json"
+	"runtime"
+	"testing"
+
+	"github.com/gookit/slog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONFormatter(t *testing.T) {
+	ctx := WithUsername(WithRequestID(context.Background(), "req-1"), "alice")
+	record := slog.New().Record()
+	record.Level = slog.InfoLevel
+	record.Message = "hello"
+	record.Ctx = ctx
+	record.Caller = &runtime.Frame{File: "/src/service.go", Line: 42}
+
+	data, err := (&JSONFormatter{}).Format(record)
+	require.NoError(t, err)
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(data, &entry))
+	require.Equal(t, "INFO", entry["level"])
+	require.Equal(t, "hello", entry["message"])
+	require.Equal(t, "req-1", entry["request_id"])
+	require.Equal(t, "alice", entry["username"])
+	require.Equal(t, "service.go:42", entry["caller"])
+
+	data, err = (&CustomFormatter{}).Format(record)
+	require.NoError(t, err)
+	require.Contains(t, string(data), "[request_id=req-1] [user=alice] hello")
+}
+
+func TestLevelSwitch(t *testing.T) {
+	defer SetIsDebugMode(IsDebugMode)
+	defer SetIsWarnMode(IsWarnMode)
+
+	SetIsDebugMode(false)
+	SetIsWarnMode(true)
+	require.False(t, isLevelEnabled(slog.DebugLevel))
+	require.True(t, isLevelEnabled(slog.WarnLevel))
+	// Ошибки записываются всегда
+	require.True(t, isLevelEnabled(slog.ErrorLevel))
+}
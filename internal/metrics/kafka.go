@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/segmentio/kafka-go"
+)
+
+var (
+	writerWritesDesc   = prometheus.NewDesc(namespace+"_kafka_writer_writes_total", "Количество запросов записи в Kafka.", nil, nil)
+	writerMessagesDesc = prometheus.NewDesc(namespace+"_kafka_writer_messages_total", "Количество сообщений, записанных в Kafka.", nil, nil)
+	writerBytesDesc    = prometheus.NewDesc(namespace+"_kafka_writer_bytes_total", "Объем сообщений, записанных в Kafka.", nil, nil)
+	writerErrorsDesc   = prometheus.NewDesc(namespace+"_kafka_writer_errors_total", "Количество ошибок записи в Kafka.", nil, nil)
+	writerRetriesDesc  = prometheus.NewDesc(namespace+"_kafka_writer_retries_total", "Количество повторов записи в Kafka.", nil, nil)
+	writerWriteAvgDesc = prometheus.NewDesc(namespace+"_kafka_writer_write_seconds_avg", "Среднее время записи в Kafka с прошлого сбора метрик.", nil, nil)
+	writerWriteMaxDesc = prometheus.NewDesc(namespace+"_kafka_writer_write_seconds_max", "Максимальное время записи в Kafka с прошлого сбора метрик.", nil, nil)
+
+	readerMessagesDesc = prometheus.NewDesc(namespace+"_kafka_reader_messages_total", "Количество сообщений, прочитанных из Kafka.", []string{"topic"}, nil)
+	readerBytesDesc    = prometheus.NewDesc(namespace+"_kafka_reader_bytes_total", "Объем сообщений, прочитанных из Kafka.", []string{"topic"}, nil)
+	readerErrorsDesc   = prometheus.NewDesc(namespace+"_kafka_reader_errors_total", "Количество ошибок чтения из Kafka.", []string{"topic"}, nil)
+	readerOffsetDesc   = prometheus.NewDesc(namespace+"_kafka_reader_offset", "Текущее смещение консьюмера.", []string{"topic"}, nil)
+	readerLagDesc      = prometheus.NewDesc(namespace+"_kafka_reader_lag", "Отставание консьюмера от конца раздела.", []string{"topic"}, nil)
+	readerQueueDesc    = prometheus.NewDesc(namespace+"_kafka_reader_queue_length", "Количество сообщений во внутренней очереди консьюмера.", []string{"topic"}, nil)
+)
+
+// readerTotals - накопленные значения счетчиков консьюмера.
+type readerTotals struct {
+	messages, bytes, errors float64
+}
+
+// kafkaCollector публикует статистику kafka.Writer и kafka.Reader. Методы Stats() возвращают
+// приращения счетчиков с предыдущего вызова, поэтому коллектор накапливает их сам.
+type kafkaCollector struct {
+	mu      sync.Mutex
+	writer  *kafka.Writer
+	readers map[string]*kafka.Reader
+
+	writerWrites, writerMessages, writerBytes, writerErrors, writerRetries float64
+	readerTotals                                                           map[string]*readerTotals
+}
+
+var kafkaStats = &kafkaCollector{
+	readers:      make(map[string]*kafka.Reader),
+	readerTotals: make(map[string]*readerTotals),
+}
+
+func init() {
+	prometheus.MustRegister(kafkaStats)
+}
+
+// RegisterKafkaWriter подключает статистику писателя Kafka.
+func RegisterKafkaWriter(writer *kafka.Writer) {
+	kafkaStats.mu.Lock()
+	defer kafkaStats.mu.Unlock()
+	kafkaStats.writer = writer
+}
+
+// RegisterKafkaReader подключает статистику консьюмера топика.
+func RegisterKafkaReader(topic string, reader *kafka.Reader) {
+	kafkaStats.mu.Lock()
+	defer kafkaStats.mu.Unlock()
+	kafkaStats.readers[topic] = reader
+	if _, ok := kafkaStats.readerTotals[topic]; !ok {
+		kafkaStats.readerTotals[topic] = &readerTotals{}
+	}
+}
+
+// UnregisterKafkaReader отключает статистику консьюмера топика. Накопленные счетчики сохраняются.
+func UnregisterKafkaReader(topic string) {
+	kafkaStats.mu.Lock()
+	defer kafkaStats.mu.Unlock()
+	delete(kafkaStats.readers, topic)
+}
+
+func (c *kafkaCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		writerWritesDesc, writerMessagesDesc, writerBytesDesc, writerErrorsDesc, writerRetriesDesc,
+		writerWriteAvgDesc, writerWriteMaxDesc,
+		readerMessagesDesc, readerBytesDesc, readerErrorsDesc, readerOffsetDesc, readerLagDesc, readerQueueDesc,
+	} {
+		ch <- desc
+	}
+}
+
+func (c *kafkaCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.writer != nil {
+		stats := c.writer.Stats()
+		c.writerWrites += float64(stats.Writes)
+		c.writerMessages += float64(stats.Messages)
+		c.writerBytes += float64(stats.Bytes)
+		c.writerErrors += float64(stats.Errors)
+		c.writerRetries += float64(stats.Retries)
+
+		ch <- prometheus.MustNewConstMetric(writerWritesDesc, prometheus.CounterValue, c.writerWrites)
+		ch <- prometheus.MustNewConstMetric(writerMessagesDesc, prometheus.CounterValue, c.writerMessages)
+		ch <- prometheus.MustNewConstMetric(writerBytesDesc, prometheus.CounterValue, c.writerBytes)
+		ch <- prometheus.MustNewConstMetric(writerErrorsDesc, prometheus.CounterValue, c.writerErrors)
+		ch <- prometheus.MustNewConstMetric(writerRetriesDesc, prometheus.CounterValue, c.writerRetries)
+		ch <- prometheus.MustNewConstMetric(writerWriteAvgDesc, prometheus.GaugeValue, stats.WriteTime.Avg.Seconds())
+		ch <- prometheus.MustNewConstMetric(writerWriteMaxDesc, prometheus.GaugeValue, stats.WriteTime.Max.Seconds())
+	}
+
+	for topic, totals := range c.readerTotals {
+		if reader, ok := c.readers[topic]; ok {
+			stats := reader.Stats()
+			totals.messages += float64(stats.Messages)
+			totals.bytes += float64(stats.Bytes)
+			totals.errors += float64(stats.Errors)
+
+			ch <- prometheus.MustNewConstMetric(readerOffsetDesc, prometheus.GaugeValue, float64(stats.Offset), topic)
+			ch <- prometheus.MustNewConstMetric(readerLagDesc, prometheus.GaugeValue, float64(stats.Lag), topic)
+			ch <- prometheus.MustNewConstMetric(readerQueueDesc, prometheus.GaugeValue, float64(stats.QueueLength), topic)
+		}
+
+		ch <- prometheus.MustNewConstMetric(readerMessagesDesc, prometheus.CounterValue, totals.messages, topic)
+		ch <- prometheus.MustNewConstMetric(readerBytesDesc, prometheus.CounterValue, totals.bytes, topic)
+		ch <- prometheus.MustNewConstMetric(readerErrorsDesc, prometheus.CounterValue, totals.errors, topic)
+	}
+}
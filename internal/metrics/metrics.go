@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "message_service"
+
+// Причины удаления сообщений из базы данных.
+const (
+	PurgeExpired   = "expired"   // истек срок жизни
+	PurgeRetention = "retention" // политика хранения топика
+)
+
+// Стадии, на которых может завершиться ошибкой обработка сообщения.
+const (
+	StagePublish = "publish"
+	StageConsume = "consume"
+	StageProcess = "process"
+)
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Количество HTTP-запросов по маршруту и статусу.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Длительность обработки HTTP-запросов по маршруту и статусу.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	messagesPublished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_published_total",
+		Help:      "Количество сообщений, опубликованных в Kafka.",
+	}, []string{"topic"})
+
+	messagesConsumed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_consumed_total",
+		Help:      "Количество сообщений, прочитанных из Kafka и успешно обработанных.",
+	}, []string{"topic"})
+
+	processorDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "processor_duration_seconds",
+		Help:      "Длительность конвейера обработки сообщений по топику и результату.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"topic", "result"})
+
+	messagesExpired = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_expired_total",
+		Help:      "Количество сообщений, пропущенных консьюмером из-за истечения срока жизни.",
+	}, []string{"topic"})
+
+	messagesPurged = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_purged_total",
+		Help:      "Количество сообщений, удаленных из базы данных, по причине удаления.",
+	}, []string{"topic", "reason"})
+
+	messagesFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_failed_total",
+		Help:      "Количество ошибок обработки сообщений по стадиям.",
+	}, []string{"topic", "stage"})
+)
+
+// ObserveHTTPRequest учитывает завершенный HTTP-запрос.
+func ObserveHTTPRequest(method, route string, status int, duration time.Duration) {
+	code := strconv.Itoa(status)
+	httpRequests.WithLabelValues(method, route, code).Inc()
+	httpDuration.WithLabelValues(method, route, code).Observe(duration.Seconds())
+}
+
+func MessagePublished(topic string) {
+	messagesPublished.WithLabelValues(topic).Inc()
+}
+
+func MessageConsumed(topic string) {
+	messagesConsumed.WithLabelValues(topic).Inc()
+}
+
+func MessageExpired(topic string) {
+	messagesExpired.WithLabelValues(topic).Inc()
+}
+
+func MessagesPurged(topic, reason string, count int64) {
+	messagesPurged.WithLabelValues(topic, reason).Add(float64(count))
+}
+
+func MessageFailed(topic, stage string) {
+	messagesFailed.WithLabelValues(topic, stage).Inc()
+}
+
+// ObserveProcessor учитывает выполнение конвейера обработки: result - ok, dropped или error.
+func ObserveProcessor(topic, result string, duration time.Duration) {
+	processorDuration.WithLabelValues(topic, result).Observe(duration.Seconds())
+}
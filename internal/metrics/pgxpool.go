@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	poolAcquireCountDesc    = prometheus.NewDesc(namespace+"_pgxpool_acquire_total", "Количество успешных получений соединения из пула.", nil, nil)
+	poolAcquireDurationDesc = prometheus.NewDesc(namespace+"_pgxpool_acquire_duration_seconds_total", "Суммарное время ожидания соединений из пула.", nil, nil)
+	poolEmptyAcquireDesc    = prometheus.NewDesc(namespace+"_pgxpool_empty_acquire_total", "Количество получений соединения с ожиданием, когда пул был пуст.", nil, nil)
+	poolCanceledAcquireDesc = prometheus.NewDesc(namespace+"_pgxpool_canceled_acquire_total", "Количество отмененных получений соединения.", nil, nil)
+	poolAcquiredConnsDesc   = prometheus.NewDesc(namespace+"_pgxpool_acquired_conns", "Количество занятых соединений.", nil, nil)
+	poolIdleConnsDesc       = prometheus.NewDesc(namespace+"_pgxpool_idle_conns", "Количество свободных соединений.", nil, nil)
+	poolTotalConnsDesc      = prometheus.NewDesc(namespace+"_pgxpool_total_conns", "Общее количество соединений.", nil, nil)
+	poolMaxConnsDesc        = prometheus.NewDesc(namespace+"_pgxpool_max_conns", "Максимальный размер пула.", nil, nil)
+)
+
+type poolCollector struct {
+	pool *pgxpool.Pool
+}
+
+// RegisterPool подключает статистику пула соединений Postgres.
+func RegisterPool(pool *pgxpool.Pool) {
+	prometheus.MustRegister(&poolCollector{pool: pool})
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		poolAcquireCountDesc, poolAcquireDurationDesc, poolEmptyAcquireDesc, poolCanceledAcquireDesc,
+		poolAcquiredConnsDesc, poolIdleConnsDesc, poolTotalConnsDesc, poolMaxConnsDesc,
+	} {
+		ch <- desc
+	}
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+
+	ch <- prometheus.MustNewConstMetric(poolAcquireCountDesc, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolAcquireDurationDesc, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(poolEmptyAcquireDesc, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolCanceledAcquireDesc, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolAcquiredConnsDesc, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(poolIdleConnsDesc, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(poolTotalConnsDesc, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(poolMaxConnsDesc, prometheus.GaugeValue, float64(stat.MaxConns()))
+}
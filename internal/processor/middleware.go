@@ -0,0 +1,73 @@
+package processor
+
+import (
+	"ProjectMessageService/internal/metrics"
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/gookit/slog"
+)
+
+// Результаты конвейера в метрике processor_duration_seconds.
+const (
+	ResultOK      = "ok"
+	ResultDropped = "dropped"
+	ResultError   = "error"
+)
+
+// Recover превращает панику шага в ошибку, чтобы она не останавливала консьюмер топика.
+func Recover(log *slog.Logger) Middleware {
+	return func(next Processor) Processor {
+		return Func(func(ctx context.Context, env *Envelope) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					log.WithCtx(ctx).Errorf("Паника при обработке сообщения %d топика %s: %v\n%s", env.ID, env.Message.Topic, r, debug.Stack())
+					err = fmt.Errorf("processor panic: %v", r)
+				}
+			}()
+			return next.Process(ctx, env)
+		})
+	}
+}
+
+// Logging записывает в журнал результат и длительность конвейера.
+func Logging(log *slog.Logger) Middleware {
+	return func(next Processor) Processor {
+		return Func(func(ctx context.Context, env *Envelope) error {
+			started := time.Now()
+			err := next.Process(ctx, env)
+			switch {
+			case errors.Is(err, ErrDrop):
+				log.WithCtx(ctx).Infof("Сообщение %d топика %s отброшено конвейером", env.ID, env.Message.Topic)
+			case err != nil:
+				log.WithCtx(ctx).Errorf("Конвейер топика %s не обработал сообщение %d: %v", env.Message.Topic, env.ID, err)
+			default:
+				log.WithCtx(ctx).Debugf("Конвейер топика %s обработал сообщение %d за %s", env.Message.Topic, env.ID, time.Since(started))
+			}
+			return err
+		})
+	}
+}
+
+// Metrics учитывает длительность и результат конвейера.
+func Metrics() Middleware {
+	return func(next Processor) Processor {
+		return Func(func(ctx context.Context, env *Envelope) error {
+			started := time.Now()
+			err := next.Process(ctx, env)
+
+			result := ResultOK
+			switch {
+			case errors.Is(err, ErrDrop):
+				result = ResultDropped
+			case err != nil:
+				result = ResultError
+			}
+			metrics.ObserveProcessor(env.Message.Topic, result, time.Since(started))
+			return err
+		})
+	}
+}
@@ -0,0 +1,64 @@
+// Package processor описывает конвейеры обработки сообщений топиков: шаги проверки, обогащения,
+// преобразования и маршрутизации, которые консьюмер выполняет перед отметкой сообщения обработанным.
+package processor
+
+import (
+	"ProjectMessageService/internal/utils"
+	"context"
+	"errors"
+)
+
+// ErrDrop возвращается шагом, чтобы прекратить обработку сообщения без ошибки:
+// сообщение отмечается обработанным, но не доставляется подписчикам.
+var ErrDrop = errors.New("message dropped")
+
+// Envelope - сообщение в конвейере обработки. Шаги могут изменять Message и Headers,
+// подписчики получат сообщение в том виде, в каком его оставил последний шаг.
+type Envelope struct {
+	ID      int // id строки в таблице топика
+	Attempt int // номер попытки обработки, начиная с 1
+	Message utils.Message
+}
+
+// SetHeader добавляет заголовок сообщению.
+func (e *Envelope) SetHeader(key, value string) {
+	if e.Message.Headers == nil {
+		e.Message.Headers = make(map[string]string)
+	}
+	e.Message.Headers[key] = value
+}
+
+// Processor - шаг или конвейер обработки сообщения.
+type Processor interface {
+	Process(ctx context.Context, env *Envelope) error
+}
+
+// Func позволяет использовать функцию как Processor.
+type Func func(ctx context.Context, env *Envelope) error
+
+func (f Func) Process(ctx context.Context, env *Envelope) error {
+	return f(ctx, env)
+}
+
+// Middleware оборачивает Processor, например для журналирования, метрик или перехвата паники.
+type Middleware func(next Processor) Processor
+
+// Pipeline выполняет шаги по порядку до первой ошибки или ErrDrop.
+type Pipeline []Processor
+
+func (p Pipeline) Process(ctx context.Context, env *Envelope) error {
+	for _, step := range p {
+		if err := step.Process(ctx, env); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Chain оборачивает p в middleware так, что первый из них выполняется первым.
+func Chain(p Processor, middleware ...Middleware) Processor {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		p = middleware[i](p)
+	}
+	return p
+}
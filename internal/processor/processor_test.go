@@ -0,0 +1,95 @@
+package processor
+
+import (
+	"ProjectMessageService/internal/utils"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gookit/slog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryConfigure(t *testing.T) {
+	registry := NewRegistry()
+	var calls []string
+	registry.RegisterStep("drop", Func(func(ctx context.Context, env *Envelope) error {
+		calls = append(calls, "drop")
+		return ErrDrop
+	}))
+	registry.RegisterStep("never", Func(func(ctx context.Context, env *Envelope) error {
+		calls = append(calls, "never")
+		return nil
+	}))
+
+	require.Error(t, registry.Configure(map[string][]string{"message": {"missing"}}))
+	require.NoError(t, registry.Configure(map[string][]string{"message": {"trim", "enrich", "drop", "never"}}))
+
+	env := &Envelope{ID: 7, Attempt: 1, Message: utils.Message{Topic: "message", Message: "  hi  "}}
+	err := registry.Get("message").Process(context.Background(), env)
+	require.True(t, errors.Is(err, ErrDrop))
+	require.Equal(t, []string{"drop"}, calls)
+	require.Equal(t, "hi", env.Message.Message)
+	require.Equal(t, "7", env.Message.Headers[HeaderMessageID])
+
+	// Топик без конвейера обрабатывается без изменений
+	env = &Envelope{Message: utils.Message{Topic: "ping", Message: " ping "}}
+	require.NoError(t, registry.Get("ping").Process(context.Background(), env))
+	require.Equal(t, " ping ", env.Message.Message)
+}
+
+func TestRecover(t *testing.T) {
+	registry := NewRegistry()
+	registry.Use(Recover(slog.New()))
+	registry.Register("message", Func(func(ctx context.Context, env *Envelope) error {
+		panic("boom")
+	}))
+
+	err := registry.Get("message").Process(context.Background(), &Envelope{Message: utils.Message{Topic: "message"}})
+	require.EqualError(t, err, "processor panic: boom")
+}
+
+func TestRegistryFinalSteps(t *testing.T) {
+	registry := NewRegistry()
+	var calls []string
+	step := func(name string) Processor {
+		return Func(func(ctx context.Context, env *Envelope) error {
+			calls = append(calls, name)
+			return nil
+		})
+	}
+	registry.RegisterStep("mark", step("mark"))
+	registry.RegisterFinalStep("route", step("route"))
+	registry.RegisterFinalStep("store", step("store"))
+
+	// Завершающие шаги выполняются после шагов конвейера, если их место не указано явно
+	require.NoError(t, registry.Configure(map[string][]string{"message": {"mark"}, "ping": {"store", "mark"}}))
+	require.NoError(t, registry.Get("message").Process(context.Background(), &Envelope{}))
+	require.Equal(t, []string{"mark", "route", "store"}, calls)
+
+	calls = nil
+	require.NoError(t, registry.Get("ping").Process(context.Background(), &Envelope{}))
+	require.Equal(t, []string{"store", "mark", "route"}, calls)
+
+	calls = nil
+	registry.Register("message", step("code"))
+	require.NoError(t, registry.Get("message").Process(context.Background(), &Envelope{}))
+	require.Equal(t, []string{"code", "route", "store"}, calls)
+
+	calls = nil
+	require.NoError(t, registry.Get("other").Process(context.Background(), &Envelope{}))
+	require.Equal(t, []string{"route", "store"}, calls)
+}
+
+func TestCanonicalPayload(t *testing.T) {
+	message := utils.Message{Payload: []byte(`{ "b": 12345678901234567890, "a": {"y": "<x>", "x": [1, 2.50]} }`)}
+	require.NoError(t, CanonicalPayload(&message))
+	require.JSONEq(t, `{"a":{"x":[1,2.50],"y":"<x>"},"b":12345678901234567890}`, string(message.Payload))
+	require.Equal(t, `{"a":{"x":[1,2.50],"y":"<x>"},"b":12345678901234567890}`, string(message.Payload))
+
+	text := utils.Message{Message: " text "}
+	require.NoError(t, CanonicalPayload(&text))
+	require.Equal(t, " text ", text.Message)
+
+	require.Error(t, CanonicalPayload(&utils.Message{Payload: []byte(`{"a":`)}))
+}
@@ -0,0 +1,127 @@
+package processor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Registry хранит именованные шаги и конвейеры топиков. Конвейер топика можно зарегистрировать
+// в коде (Register) или собрать из имен шагов (Configure, параметр PROCESSORS).
+type Registry struct {
+	mu         sync.RWMutex
+	steps      map[string]Processor
+	final      []string
+	topics     map[string]Processor
+	configured map[string][]string // Имена шагов конвейеров из Configure
+	middleware []Middleware
+}
+
+// NewRegistry создает реестр со встроенными шагами trim, enrich и transform.
+func NewRegistry() *Registry {
+	r := &Registry{steps: make(map[string]Processor), topics: make(map[string]Processor), configured: make(map[string][]string)}
+	r.RegisterStep("trim", Func(Trim))
+	r.RegisterStep("enrich", Func(Enrich))
+	r.RegisterStep("transform", Transform(CanonicalPayload))
+	return r
+}
+
+// RegisterFinalStep добавляет шаг, который выполняется в конце конвейера каждого топика.
+// Конвейер из Configure может указать место шага явно, тогда шаг не добавляется в конец.
+func (r *Registry) RegisterFinalStep(name string, step Processor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.steps[name] = step
+	r.final = append(r.final, name)
+}
+
+// RegisterStep добавляет шаг, доступный по имени в Configure.
+func (r *Registry) RegisterStep(name string, step Processor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.steps[name] = step
+}
+
+// Register задает конвейер топика, заменяя предыдущий.
+func (r *Registry) Register(topic string, p Processor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.topics[topic] = p
+	delete(r.configured, topic)
+}
+
+// Use добавляет middleware, которыми оборачивается конвейер каждого топика.
+func (r *Registry) Use(middleware ...Middleware) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.middleware = append(r.middleware, middleware...)
+}
+
+// Configure собирает конвейеры топиков из имен зарегистрированных шагов.
+func (r *Registry) Configure(pipelines map[string][]string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	built := make(map[string]Processor, len(pipelines))
+	for topic, names := range pipelines {
+		pipeline := make(Pipeline, 0, len(names))
+		for _, name := range names {
+			step, ok := r.steps[name]
+			if !ok {
+				return fmt.Errorf("topic %s: unknown processor step %q, available: %s", topic, name, strings.Join(r.stepNames(), ", "))
+			}
+			pipeline = append(pipeline, step)
+		}
+		built[topic] = pipeline
+	}
+	for topic, pipeline := range built {
+		r.topics[topic] = pipeline
+		r.configured[topic] = pipelines[topic]
+	}
+	return nil
+}
+
+// Get возвращает конвейер топика с завершающими шагами, обернутый в middleware. Для топика без конвейера
+// выполняются только завершающие шаги.
+func (r *Registry) Get(topic string) Processor {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	p, ok := r.topics[topic]
+	if !ok {
+		p = Pipeline{}
+	}
+	pipeline := Pipeline{p}
+	for _, name := range r.final {
+		if !contains(r.configured[topic], name) {
+			pipeline = append(pipeline, r.steps[name])
+		}
+	}
+	return Chain(pipeline, r.middleware...)
+}
+
+// Steps возвращает имена зарегистрированных шагов.
+func (r *Registry) Steps() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.stepNames()
+}
+
+func (r *Registry) stepNames() []string {
+	names := make([]string, 0, len(r.steps))
+	for name := range r.steps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package processor
+
+import (
+	"ProjectMessageService/internal/utils"
+	"bytes"
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Заголовки, которые добавляет шаг enrich.
+const (
+	HeaderProcessedAt = "x-processed-at"
+	HeaderAttempt     = "x-processing-attempt"
+	HeaderMessageID   = "x-message-id"
+)
+
+// Trim удаляет пробельные символы по краям текста сообщения. Сообщения с payload не изменяются.
+func Trim(_ context.Context, env *Envelope) error {
+	env.Message.Message = strings.TrimSpace(env.Message.Message)
+	return nil
+}
+
+// Enrich добавляет заголовки с id сообщения, номером попытки и временем обработки.
+func Enrich(_ context.Context, env *Envelope) error {
+	env.SetHeader(HeaderMessageID, strconv.Itoa(env.ID))
+	env.SetHeader(HeaderAttempt, strconv.Itoa(env.Attempt))
+	env.SetHeader(HeaderProcessedAt, time.Now().UTC().Format(time.RFC3339Nano))
+	return nil
+}
+
+// Transform возвращает шаг, изменяющий сообщение функцией fn.
+func Transform(fn func(message *utils.Message) error) Processor {
+	return Func(func(_ context.Context, env *Envelope) error {
+		return fn(&env.Message)
+	})
+}
+
+// CanonicalPayload приводит JSON payload к каноническому виду: без пробелов, ключи объектов по алфавиту.
+// Числа сохраняются без потери точности. Сообщения без payload не изменяются.
+func CanonicalPayload(message *utils.Message) error {
+	if len(message.Payload) == 0 {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(message.Payload))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return err
+	}
+	var payload bytes.Buffer
+	encoder := json.NewEncoder(&payload)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	message.Payload = bytes.TrimSuffix(payload.Bytes(), []byte("\n"))
+	return nil
+}
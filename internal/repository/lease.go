@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"ProjectMessageService/internal/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+// LeasedMessage - сообщение, выданное HTTP-консьюмеру во временную аренду.
+type LeasedMessage struct {
+	LeaseID uuid.UUID `json:"lease_id"`
+	ID      int       `json:"id"`
+	utils.Message
+	CreatedAt   time.Time `json:"created_at"`
+	LeasedUntil time.Time `json:"leased_until"`
+}
+
+const upsertLease = `-- name: UpsertLease :exec
+INSERT INTO consumer_leases (
+    topic,
+    consumer,
+    message_id,
+    lease_id,
+    leased_until
+) VALUES (
+    $1, $2, $3, $4, $5
+) ON CONFLICT (topic, consumer, message_id) DO UPDATE
+SET lease_id = EXCLUDED.lease_id, leased_until = EXCLUDED.leased_until
+`
+
+// lockConsumer блокирует выдачу сообщений консьюмеру топика до конца транзакции.
+const lockConsumer = `SELECT pg_advisory_xact_lock(hashtext($1), hashtext($2))`
+
+// ClaimMessages выдает консьюмеру до limit обработанных сообщений топика, которые он еще не подтвердил
+// и которые не находятся в действующей аренде. Каждое выданное сообщение получает новый lease_id.
+// Сообщения выдаются в том виде, в каком их сохранил шаг store конвейера обработки.
+// Одновременные запросы одного консьюмера топика выполняются по очереди под advisory-блокировкой,
+// поэтому сообщения выдаются в порядке id: следующий запрос видит аренды предыдущего.
+func (r *Repository) ClaimMessages(ctx context.Context, topic, consumer string, limit int, leaseDuration time.Duration) ([]LeasedMessage, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	if _, err = tx.Exec(ctx, lockConsumer, topic, consumer); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`SELECT m.id, m.content, m.payload, m.message_key, m.headers, m.content_type, m.output, m.created_at FROM %s m
+WHERE m.processed = true
+AND (m.expires_at IS NULL OR m.expires_at > now())
+AND NOT EXISTS (
+    SELECT 1 FROM consumer_leases l
+    WHERE l.topic = $1 AND l.consumer = $2 AND l.message_id = m.id
+    AND (l.acked_at IS NOT NULL OR l.leased_until > now())
+)
+ORDER BY m.id
+LIMIT $3`, topic)
+
+	rows, err := tx.Query(ctx, query, topic, consumer, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	leasedUntil := time.Now().Add(leaseDuration)
+	var items []LeasedMessage
+	for rows.Next() {
+		i := LeasedMessage{LeasedUntil: leasedUntil}
+		i.Topic = topic
+		var payload, headers, output []byte
+		if err = rows.Scan(&i.ID, &i.Message.Message, &payload, &i.Key, &headers, &i.ContentType, &output, &i.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if len(output) > 0 {
+			err = json.Unmarshal(output, &i.Message)
+		} else {
+			err = scanMessageColumns(&i.Message, payload, headers)
+		}
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	batch := &pgx.Batch{}
+	for idx := range items {
+		items[idx].LeaseID = uuid.New()
+		batch.Queue(upsertLease, topic, consumer, items[idx].ID, items[idx].LeaseID, leasedUntil)
+	}
+	if len(items) > 0 {
+		if err = tx.SendBatch(ctx, batch).Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	return items, tx.Commit(ctx)
+}
+
+const ackLeases = `-- name: AckLeases :execrows
+UPDATE consumer_leases SET acked_at = now()
+WHERE topic = $1 AND consumer = $2 AND lease_id = ANY($3::uuid[])
+AND acked_at IS NULL AND leased_until > now()
+`
+
+// AckLeases подтверждает обработку сообщений по действующим арендам. Возвращает число подтвержденных.
+func (r *Repository) AckLeases(ctx context.Context, topic, consumer string, leaseIDs []string) (int64, error) {
+	tag, err := r.db.Exec(ctx, ackLeases, topic, consumer, leaseIDs)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+const nackLeases = `-- name: NackLeases :execrows
+UPDATE consumer_leases SET leased_until = now()
+WHERE topic = $1 AND consumer = $2 AND lease_id = ANY($3::uuid[])
+AND acked_at IS NULL AND leased_until > now()
+`
+
+// NackLeases досрочно завершает аренды, чтобы сообщения были выданы повторно.
+func (r *Repository) NackLeases(ctx context.Context, topic, consumer string, leaseIDs []string) (int64, error) {
+	tag, err := r.db.Exec(ctx, nackLeases, topic, consumer, leaseIDs)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
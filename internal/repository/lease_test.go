@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"ProjectMessageService/internal/utils"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClaimMessagesLeases(t *testing.T) {
+	r := newTestRepository(t)
+	ctx := context.Background()
+
+	for _, content := range []string{"lease 1", "lease 2", "not processed"} {
+		message := utils.Message{Topic: testTopic, Message: content}
+		require.NoError(t, r.SaveMessage(ctx, message))
+		if content == "not processed" {
+			continue
+		}
+		id, err := r.ContentMessagesKey(ctx, message)
+		require.NoError(t, err)
+		require.NoError(t, r.MarkMessageAsProcessed(ctx, message, id))
+	}
+
+	// Выдаются только обработанные сообщения
+	leased, err := r.ClaimMessages(ctx, testTopic, "c1", 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, leased, 2)
+	require.Equal(t, "lease 1", leased[0].Message.Message)
+
+	// Сообщения в действующей аренде повторно не выдаются, но доступны другому консьюмеру
+	again, err := r.ClaimMessages(ctx, testTopic, "c1", 10, time.Minute)
+	require.NoError(t, err)
+	require.Empty(t, again)
+	other, err := r.ClaimMessages(ctx, testTopic, "c2", 1, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, other, 1)
+
+	// Подтвержденное сообщение больше не выдается, возвращенное - выдается снова
+	count, err := r.AckLeases(ctx, testTopic, "c1", []string{leased[0].LeaseID.String()})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, count)
+	count, err = r.NackLeases(ctx, testTopic, "c1", []string{leased[1].LeaseID.String()})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, count)
+	again, err = r.ClaimMessages(ctx, testTopic, "c1", 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, again, 1)
+	require.Equal(t, leased[1].ID, again[0].ID)
+
+	// Возвращенную аренду подтвердить уже нельзя
+	count, err = r.AckLeases(ctx, testTopic, "c1", []string{leased[1].LeaseID.String()})
+	require.NoError(t, err)
+	require.Zero(t, count)
+
+	// После истечения аренды сообщение выдается повторно
+	_, err = r.db.Exec(ctx, `UPDATE consumer_leases SET leased_until = now() - interval '1 second' WHERE lease_id = $1`, again[0].LeaseID)
+	require.NoError(t, err)
+	again, err = r.ClaimMessages(ctx, testTopic, "c1", 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, again, 1)
+}
+
+func TestClaimMessagesConcurrentOrder(t *testing.T) {
+	r := newTestRepository(t)
+	ctx := context.Background()
+
+	for i := 0; i < 4; i++ {
+		message := utils.Message{Topic: testTopic, Message: fmt.Sprintf("ordered %d", i)}
+		require.NoError(t, r.SaveMessage(ctx, message))
+		id, err := r.ContentMessagesKey(ctx, message)
+		require.NoError(t, err)
+		require.NoError(t, r.MarkMessageAsProcessed(ctx, message, id))
+	}
+
+	// Пока первый запрос не зафиксирован, второй ждет его и не получает более поздние сообщения раньше
+	tx, err := r.db.Begin(ctx)
+	require.NoError(t, err)
+	_, err = tx.Exec(ctx, lockConsumer, testTopic, "c1")
+	require.NoError(t, err)
+
+	claimed := make(chan []LeasedMessage, 1)
+	go func() {
+		leased, err := r.ClaimMessages(ctx, testTopic, "c1", 2, time.Minute)
+		require.NoError(t, err)
+		claimed <- leased
+	}()
+	select {
+	case <-claimed:
+		t.Fatal("claim did not wait for the consumer lock")
+	case <-time.After(100 * time.Millisecond):
+	}
+	require.NoError(t, tx.Rollback(ctx))
+
+	leased := <-claimed
+	require.Len(t, leased, 2)
+	require.Equal(t, "ordered 0", leased[0].Message.Message)
+	next, err := r.ClaimMessages(ctx, testTopic, "c1", 2, time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, "ordered 2", next[0].Message.Message)
+}
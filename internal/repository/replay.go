@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"ProjectMessageService/internal/utils"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// StoredMessage - сообщение топика, сохраненное в базе данных.
+type StoredMessage struct {
+	ID int `json:"id"`
+	utils.Message
+	Processed bool      `json:"processed"`
+	Attempts  int       `json:"attempts"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ListMessagesParams - фильтр сообщений топика. Нулевые значения полей не ограничивают выборку.
+type ListMessagesParams struct {
+	Topic           string
+	AfterID         int
+	From            time.Time // Сообщения, созданные не раньше; нулевое значение не ограничивает начало
+	To              time.Time // Сообщения, созданные раньше; нулевое значение не ограничивает конец
+	OnlyUnprocessed bool
+	Limit           int
+}
+
+// ValidateWindow проверяет, что интервал From-To не пуст, если заданы обе границы.
+func (p ListMessagesParams) ValidateWindow() error {
+	if !p.From.IsZero() && !p.To.IsZero() && !p.To.After(p.From) {
+		return errors.New("to must be after from")
+	}
+	return nil
+}
+
+// ListMessages возвращает сообщения топика по фильтру в порядке id.
+func (r *Repository) ListMessages(ctx context.Context, arg ListMessagesParams) ([]StoredMessage, error) {
+	conditions := []string{"id > $1"}
+	args := []interface{}{arg.AfterID}
+	if !arg.From.IsZero() {
+		args = append(args, arg.From)
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if !arg.To.IsZero() {
+		args = append(args, arg.To)
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+	if arg.OnlyUnprocessed {
+		conditions = append(conditions, "processed IS NOT TRUE")
+	}
+	args = append(args, arg.Limit)
+
+	query := fmt.Sprintf(`SELECT %s FROM %s
+WHERE %s
+ORDER BY id
+LIMIT $%d`, storedMessageColumns, arg.Topic, strings.Join(conditions, " AND "), len(args))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []StoredMessage
+	for rows.Next() {
+		i, err := scanStoredMessage(rows, arg.Topic)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	return items, rows.Err()
+}
+
+// storedMessageColumns - колонки таблицы топика, которые читает scanStoredMessage.
+const storedMessageColumns = `id, content, payload, message_key, headers, content_type, expires_at, priority, processed, attempts, created_at`
+
+func scanStoredMessage(row pgx.Row, topic string) (StoredMessage, error) {
+	var i StoredMessage
+	i.Topic = topic
+	var payload, headers []byte
+	var processed *bool
+	if err := row.Scan(&i.ID, &i.Message.Message, &payload, &i.Key, &headers, &i.ContentType, &i.ExpiresAt, &i.Priority, &processed, &i.Attempts, &i.CreatedAt); err != nil {
+		return i, err
+	}
+	i.Processed = processed != nil && *processed
+	return i, scanMessageColumns(&i.Message, payload, headers)
+}
+
+// BeginProcessing захватывает сообщение id для обработки на время lease, засчитывает попытку и возвращает их число.
+// Второе значение - false, если сообщение уже обработано и не запрошена его повторная обработка
+// или его обрабатывает другая доставка: так повторная доставка из Kafka после сброса смещения или
+// одновременная доставка на другую реплику не обрабатывает сообщение дважды. Захват снимают
+// MarkMessageAsProcessed и MarkMessageAsFailed, а если обработчик остановился - истечение lease.
+func (r *Repository) BeginProcessing(ctx context.Context, topic string, id int, lease time.Duration) (int, bool, error) {
+	query := fmt.Sprintf(`UPDATE %s SET attempts = attempts + 1, last_attempt_at = now(), processing_until = now() + $2::interval
+WHERE id = $1 AND (processed IS NOT TRUE OR reprocess) AND (processing_until IS NULL OR processing_until < now())
+RETURNING attempts`, topic)
+
+	var attempts int
+	err := r.db.QueryRow(ctx, query, id, lease).Scan(&attempts)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	return attempts, err == nil, err
+}
+
+// RequestReprocess разрешает повторную обработку сообщений топика с перечисленными id.
+func (r *Repository) RequestReprocess(ctx context.Context, topic string, ids []int) (int64, error) {
+	query := fmt.Sprintf(`UPDATE %s SET reprocess = true WHERE id = ANY($1) AND processed`, topic)
+	tag, err := r.db.Exec(ctx, query, ids)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// RequestReprocessSince разрешает повторную обработку сообщений топика, созданных не раньше from.
+func (r *Repository) RequestReprocessSince(ctx context.Context, topic string, from time.Time) (int64, error) {
+	query := fmt.Sprintf(`UPDATE %s SET reprocess = true WHERE created_at >= $1 AND processed`, topic)
+	tag, err := r.db.Exec(ctx, query, from)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
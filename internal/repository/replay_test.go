@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"ProjectMessageService/internal/utils"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBeginProcessing(t *testing.T) {
+	r := newTestRepository(t)
+	ctx := context.Background()
+
+	message := utils.Message{Topic: testTopic, Message: "begin processing"}
+	require.NoError(t, r.SaveMessage(ctx, message))
+	id, err := r.ContentMessagesKey(ctx, message)
+	require.NoError(t, err)
+
+	// Из одновременных доставок сообщение захватывает только одна
+	var wg sync.WaitGroup
+	claimed := make(chan int, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			attempts, ok, err := r.BeginProcessing(ctx, testTopic, id, time.Minute)
+			require.NoError(t, err)
+			if ok {
+				claimed <- attempts
+			}
+		}()
+	}
+	wg.Wait()
+	close(claimed)
+	require.Len(t, claimed, 1)
+	require.Equal(t, 1, <-claimed)
+
+	// Ошибка снимает захват, следующая попытка засчитывается
+	require.NoError(t, r.MarkMessageAsFailed(ctx, testTopic, id, context.DeadlineExceeded))
+	attempts, ok, err := r.BeginProcessing(ctx, testTopic, id, time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, 2, attempts)
+
+	// Обработанное сообщение пропускается, пока не запрошена повторная обработка
+	require.NoError(t, r.MarkMessageAsProcessed(ctx, message, id))
+	_, ok, err = r.BeginProcessing(ctx, testTopic, id, time.Minute)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	count, err := r.RequestReprocess(ctx, testTopic, []int{id})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, count)
+	attempts, ok, err = r.BeginProcessing(ctx, testTopic, id, time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, 3, attempts)
+
+	// Захват, не снятый остановившимся обработчиком, истекает
+	_, ok, err = r.BeginProcessing(ctx, testTopic, id, time.Minute)
+	require.NoError(t, err)
+	require.False(t, ok)
+	_, err = r.db.Exec(ctx, `UPDATE `+testTopic+` SET processing_until = now() - interval '1 second' WHERE id = $1`, id)
+	require.NoError(t, err)
+	attempts, ok, err = r.BeginProcessing(ctx, testTopic, id, time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, 4, attempts)
+}
+
+func TestListAndCountMessages(t *testing.T) {
+	r := newTestRepository(t)
+	ctx := context.Background()
+
+	var ids []int
+	for _, content := range []string{"list 1", "list 2", "list 3"} {
+		message := utils.Message{Topic: testTopic, Message: content}
+		require.NoError(t, r.SaveMessage(ctx, message))
+		id, err := r.ContentMessagesKey(ctx, message)
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+	require.NoError(t, r.MarkMessageAsProcessed(ctx, utils.Message{Topic: testTopic, Message: "list 1"}, ids[0]))
+
+	counts, err := r.CountMessages(ctx, testTopic)
+	require.NoError(t, err)
+	require.Equal(t, MessageCounts{TableExists: true, Total: 3, Processed: 1}, counts)
+	counts, err = r.CountMessages(ctx, "repotest_missing")
+	require.NoError(t, err)
+	require.False(t, counts.TableExists)
+
+	messages, err := r.ListMessages(ctx, ListMessagesParams{Topic: testTopic, AfterID: ids[0], Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	require.Equal(t, ids[1], messages[0].ID)
+
+	messages, err = r.ListMessages(ctx, ListMessagesParams{Topic: testTopic, OnlyUnprocessed: true, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	require.Equal(t, "list 2", messages[0].Message.Message)
+
+	messages, err = r.ListMessages(ctx, ListMessagesParams{Topic: testTopic, To: time.Now().Add(-time.Hour), Limit: 10})
+	require.NoError(t, err)
+	require.Empty(t, messages)
+}
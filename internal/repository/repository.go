@@ -3,11 +3,14 @@ package repository
 import (
 	`ProjectMessageService/config`
 	`ProjectMessageService/internal/api`
+	"ProjectMessageService/internal/tracing"
 	`ProjectMessageService/internal/utils`
 	`context`
+	"encoding/json"
 	`fmt`
 	`time`
 
+	`github.com/jackc/pgx/v4`
 	`github.com/jackc/pgx/v4/pgxpool`
 )
 
@@ -32,11 +35,18 @@ func NewPostgresDB(cfg config.Config, maxAttempts int) (pool *pgxpool.Pool, err
 	dsn := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
 		cfg.DBUser, cfg.DBPassword, cfg.DBHost, cfg.DBPort, cfg.DBName)
 
+	poolConfig, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+	// Спаны запросов строятся по журналу pgx
+	poolConfig.ConnConfig.Logger = tracing.QueryLogger{}
+
 	err = utils.TimeConnect(func() error {
 		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 		defer cancel()
 
-		pool, err = pgxpool.Connect(ctx, dsn)
+		pool, err = pgxpool.ConnectConfig(ctx, poolConfig)
 		if err != nil {
 			return err
 		}
@@ -51,34 +61,44 @@ func NewPostgresDB(cfg config.Config, maxAttempts int) (pool *pgxpool.Pool, err
 	return pool, nil
 }
 
+// Ping проверяет доступность базы данных.
+func (r *Repository) Ping(ctx context.Context) error {
+	return r.db.Ping(ctx)
+}
+
 func (r *Repository) SaveMessage(ctx context.Context, message utils.Message) error {
 
 	tableName := message.Topic
 	exists, err := r.tableExists(ctx, tableName)
 	if err != nil {
-		r.app.Log.Errorf("Error checking if table exists: %s", err)
+		r.app.Log.WithCtx(ctx).Errorf("Error checking if table exists: %s", err)
 		return err
 	}
 
 	if !exists {
-		r.app.Log.Errorf("ошибка нет такой таблицы: %s", tableName)
+		r.app.Log.WithCtx(ctx).Errorf("ошибка нет такой таблицы: %s", tableName)
 		err = fmt.Errorf("ошибка нет такой таблицы %s", tableName)
 		return err
 	}
 
 	var count bool
 	// Динамическое имя таблицы встроено в запрос с помощью форматирования строки
-	query := fmt.Sprintf(`SELECT EXISTS (SELECT FROM %s WHERE content = $1)`, message.Topic)
-	err = r.db.QueryRow(ctx, query, message.Message).Scan(&count)
+	query := fmt.Sprintf(`SELECT EXISTS (SELECT FROM %s WHERE content = $1 AND routed_from = $2)`, message.Topic)
+	err = r.db.QueryRow(ctx, query, message.Content(), message.RoutedFrom()).Scan(&count)
 
 	if err != nil {
 		return err
 	}
 
 	if !count {
+		args, err := messageArgs(message)
+		if err != nil {
+			return err
+		}
+
 		// Динамическое имя таблицы встроено в запрос с помощью форматирования строки
-		query = fmt.Sprintf(`INSERT INTO %s (content) VALUES ($1)`, message.Topic)
-		_, err = r.db.Exec(ctx, query, message.Message)
+		query = fmt.Sprintf(`INSERT INTO %s (content, payload, message_key, headers, content_type, expires_at, priority, routed_from) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`, message.Topic)
+		_, err = r.db.Exec(ctx, query, args...)
 		if err != nil {
 			return err
 		}
@@ -87,23 +107,76 @@ func (r *Repository) SaveMessage(ctx context.Context, message utils.Message) err
 	return nil
 }
 
-func (r *Repository) GetProcessedMessagesCount(ctx context.Context, topic utils.Messages) (int, error) {
-	var count int
-	tableName := topic.Topic
-	exists, err := r.tableExists(ctx, tableName)
+// SavedMessage - результат сохранения одного сообщения пакета.
+type SavedMessage struct {
+	ID       int
+	Inserted bool
+}
+
+// SaveMessages сохраняет пакет сообщений одной транзакцией. Сообщения с уже существующим содержимым
+// (для копий маршрутизации - содержимым и исходным сообщением routed_from) не вставляются повторно, для них возвращается id существующей записи и Inserted = false.
+// Топики сообщений должны быть проверены заранее.
+func (r *Repository) SaveMessages(ctx context.Context, messages []utils.Message) ([]SavedMessage, error) {
+	saved, _, err := r.SaveMessageBatch(ctx, messages, nil)
+	return saved, err
+}
+
+// SaveMessageBatch сохраняет сообщения (как SaveMessages) и отложенные сообщения scheduled одной транзакцией.
+func (r *Repository) SaveMessageBatch(ctx context.Context, messages []utils.Message, scheduled []CreateScheduledMessageParams) ([]SavedMessage, []ScheduledMessage, error) {
+	tx, err := r.db.Begin(ctx)
 	if err != nil {
-		r.app.Log.Errorf("Error checking if table exists: %s", err)
-		return count, err
+		return nil, nil, err
 	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
 
-	if !exists {
-		err = fmt.Errorf("ошибка нет такой таблицы %s", tableName)
-		return count, err
+	batch := &pgx.Batch{}
+	for _, message := range messages {
+		args, err := messageArgs(message)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// Динамическое имя таблицы встроено в запрос с помощью форматирования строки
+		query := fmt.Sprintf(`WITH ins AS (
+    INSERT INTO %[1]s (content, payload, message_key, headers, content_type, expires_at, priority, routed_from)
+    SELECT $1, $2, $3, $4, $5, $6, $7, $8 WHERE NOT EXISTS (SELECT FROM %[1]s WHERE content = $1 AND routed_from = $8) RETURNING id
+)
+SELECT id, true FROM ins
+UNION ALL
+SELECT id, false FROM %[1]s WHERE content = $1 AND routed_from = $8 AND NOT EXISTS (SELECT FROM ins)
+LIMIT 1`, message.Topic)
+		batch.Queue(query, args...)
+	}
+	for _, arg := range scheduled {
+		args, err := scheduledMessageArgs(arg)
+		if err != nil {
+			return nil, nil, err
+		}
+		batch.Queue(createScheduledMessage, args...)
 	}
 
-	query := fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE processed = true`, topic.Topic)
-	err = r.db.QueryRow(ctx, query).Scan(&count)
-	return count, err
+	results := tx.SendBatch(ctx, batch)
+	saved := make([]SavedMessage, len(messages))
+	for i := range messages {
+		if err = results.QueryRow().Scan(&saved[i].ID, &saved[i].Inserted); err != nil {
+			_ = results.Close()
+			return nil, nil, err
+		}
+	}
+	savedScheduled := make([]ScheduledMessage, len(scheduled))
+	for i := range scheduled {
+		if savedScheduled[i], err = scanScheduledMessage(results.QueryRow()); err != nil {
+			_ = results.Close()
+			return nil, nil, err
+		}
+	}
+	if err = results.Close(); err != nil {
+		return nil, nil, err
+	}
+
+	return saved, savedScheduled, tx.Commit(ctx)
 }
 
 func RunMigrations(db *pgxpool.Pool, messages []string) error {
@@ -145,6 +218,105 @@ func RunMigrations(db *pgxpool.Pool, messages []string) error {
 		return err
 	}
 
+	if err = RunTopicMigrations(db, messages); err != nil {
+		return err
+	}
+
+	qwebhooks := `CREATE TABLE IF NOT EXISTS webhooks (
+		id BIGSERIAL PRIMARY KEY,
+		topic varchar NOT NULL,
+		url varchar NOT NULL,
+		secret varchar NOT NULL,
+		filter varchar NOT NULL DEFAULT '',
+		is_active boolean NOT NULL DEFAULT true,
+		failure_count integer NOT NULL DEFAULT 0,
+		created_at timestamptz NOT NULL DEFAULT (now())
+	);`
+	_, err = db.Exec(ctx, qwebhooks)
+	if err != nil {
+		return err
+	}
+
+	qdeliveries := `CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id BIGSERIAL PRIMARY KEY,
+		webhook_id bigint NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+		message_id integer NOT NULL,
+		attempt integer NOT NULL,
+		status_code integer NOT NULL DEFAULT 0,
+		error varchar NOT NULL DEFAULT '',
+		success boolean NOT NULL,
+		created_at timestamptz NOT NULL DEFAULT (now())
+	);`
+	_, err = db.Exec(ctx, qdeliveries)
+	if err != nil {
+		return err
+	}
+
+	qleases := `CREATE TABLE IF NOT EXISTS consumer_leases (
+		topic varchar NOT NULL,
+		consumer varchar NOT NULL,
+		message_id integer NOT NULL,
+		lease_id uuid UNIQUE NOT NULL,
+		leased_until timestamptz NOT NULL,
+		acked_at timestamptz,
+		PRIMARY KEY (topic, consumer, message_id)
+	);`
+	_, err = db.Exec(ctx, qleases)
+	if err != nil {
+		return err
+	}
+
+	qschemas := `CREATE TABLE IF NOT EXISTS topic_schemas (
+		topic varchar NOT NULL,
+		version integer NOT NULL,
+		schema jsonb NOT NULL,
+		compatibility varchar NOT NULL,
+		created_at timestamptz NOT NULL DEFAULT (now()),
+		PRIMARY KEY (topic, version)
+	);`
+	_, err = db.Exec(ctx, qschemas)
+	if err != nil {
+		return err
+	}
+
+	qrouting := `CREATE TABLE IF NOT EXISTS routing_rules (
+		id BIGSERIAL PRIMARY KEY,
+		topic varchar NOT NULL,
+		name varchar NOT NULL DEFAULT '',
+		priority integer NOT NULL DEFAULT 0,
+		conditions jsonb NOT NULL DEFAULT '[]',
+		action varchar NOT NULL,
+		target_topic varchar NOT NULL DEFAULT '',
+		is_active boolean NOT NULL DEFAULT true,
+		created_at timestamptz NOT NULL DEFAULT (now())
+	);`
+	_, err = db.Exec(ctx, qrouting)
+	if err != nil {
+		return err
+	}
+
+	qscheduled := `CREATE TABLE IF NOT EXISTS scheduled_messages (
+		id BIGSERIAL PRIMARY KEY,
+		topic varchar NOT NULL,
+		message jsonb NOT NULL,
+		deliver_at timestamptz NOT NULL,
+		created_by varchar NOT NULL DEFAULT '',
+		created_at timestamptz NOT NULL DEFAULT (now()),
+		delivered_at timestamptz
+	);
+	ALTER TABLE scheduled_messages ADD COLUMN IF NOT EXISTS claimed_until timestamptz;
+	CREATE INDEX IF NOT EXISTS scheduled_messages_due_idx ON scheduled_messages (deliver_at) WHERE delivered_at IS NULL;`
+	_, err = db.Exec(ctx, qscheduled)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// RunTopicMigrations создает таблицы сообщений топиков, в том числе добавленных при перезагрузке конфигурации.
+func RunTopicMigrations(db *pgxpool.Pool, messages []string) error {
+	ctx := context.Background()
+
 	for _, message := range messages {
 		msg := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s ", message)
 
@@ -155,7 +327,35 @@ func RunMigrations(db *pgxpool.Pool, messages []string) error {
 		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 	    );`
 
-		_, err = db.Exec(ctx, msg+query)
+		_, err := db.Exec(ctx, msg+query)
+		if err != nil {
+			return err
+		}
+
+		qcolumns := fmt.Sprintf(`ALTER TABLE %s
+		ADD COLUMN IF NOT EXISTS payload jsonb,
+		ADD COLUMN IF NOT EXISTS message_key varchar NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS headers jsonb,
+		ADD COLUMN IF NOT EXISTS content_type varchar NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS processed_at timestamptz,
+		ADD COLUMN IF NOT EXISTS failed_at timestamptz,
+		ADD COLUMN IF NOT EXISTS last_error text NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS attempts integer NOT NULL DEFAULT 0,
+		ADD COLUMN IF NOT EXISTS last_attempt_at timestamptz,
+		ADD COLUMN IF NOT EXISTS reprocess boolean NOT NULL DEFAULT false,
+		ADD COLUMN IF NOT EXISTS processing_until timestamptz,
+		ADD COLUMN IF NOT EXISTS output jsonb,
+		ADD COLUMN IF NOT EXISTS expires_at timestamptz,
+		ADD COLUMN IF NOT EXISTS expired_at timestamptz,
+		ADD COLUMN IF NOT EXISTS priority varchar NOT NULL DEFAULT 'normal',
+		ADD COLUMN IF NOT EXISTS routed_from varchar NOT NULL DEFAULT '';`, message)
+		_, err = db.Exec(ctx, qcolumns)
+		if err != nil {
+			return err
+		}
+
+		qexpires := fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %[1]s_expires_at_idx ON %[1]s (expires_at) WHERE expires_at IS NOT NULL;`, message)
+		_, err = db.Exec(ctx, qexpires)
 		if err != nil {
 			return err
 		}
@@ -164,18 +364,73 @@ func RunMigrations(db *pgxpool.Pool, messages []string) error {
 }
 
 func (r *Repository) MarkMessageAsProcessed(ctx context.Context, message utils.Message, messageKey int) error {
-	query := fmt.Sprintf(`UPDATE %s SET processed = true WHERE id = $1`, message.Topic)
+	query := fmt.Sprintf(`UPDATE %s SET processed = true, processed_at = now(), reprocess = false, last_error = '', processing_until = NULL WHERE id = $1`, message.Topic)
+	_, err := r.db.Exec(ctx, query, messageKey)
+	return err
+}
+
+// SaveMessageOutput сохраняет сообщение messageKey в том виде, в каком его оставил конвейер обработки.
+// Исходное содержимое не изменяется: по нему определяются повторные доставки.
+func (r *Repository) SaveMessageOutput(ctx context.Context, topic string, messageKey int, message utils.Message) error {
+	output, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	query := fmt.Sprintf(`UPDATE %s SET output = $2 WHERE id = $1`, topic)
+	_, err = r.db.Exec(ctx, query, messageKey, output)
+	return err
+}
+
+// MarkMessageAsFailed отмечает сообщение id как необработанное из-за ошибки и снимает захват BeginProcessing.
+func (r *Repository) MarkMessageAsFailed(ctx context.Context, topic string, messageKey int, processErr error) error {
+	query := fmt.Sprintf(`UPDATE %s SET failed_at = now(), last_error = $2, processing_until = NULL WHERE id = $1`, topic)
+	_, err := r.db.Exec(ctx, query, messageKey, processErr.Error())
+	return err
+}
+
+// MarkMessageAsExpired отмечает необработанное сообщение как пропущенное из-за истечения срока жизни.
+func (r *Repository) MarkMessageAsExpired(ctx context.Context, topic string, messageKey int) error {
+	query := fmt.Sprintf(`UPDATE %s SET expired_at = now(), reprocess = false WHERE id = $1 AND (processed IS NOT TRUE OR reprocess)`, topic)
 	_, err := r.db.Exec(ctx, query, messageKey)
 	return err
 }
 
 func (r *Repository) ContentMessagesKey(ctx context.Context, message utils.Message) (int, error) {
 	var count int
-	query := fmt.Sprintf(`SELECT id FROM %s WHERE content = $1`, message.Topic)
-	err := r.db.QueryRow(ctx, query, message.Message).Scan(&count)
+	query := fmt.Sprintf(`SELECT id FROM %s WHERE content = $1 AND routed_from = $2`, message.Topic)
+	err := r.db.QueryRow(ctx, query, message.Content(), message.RoutedFrom()).Scan(&count)
 	return count, err
 }
 
+// messageArgs возвращает значения колонок content, payload, message_key, headers, content_type, expires_at, priority
+// и routed_from.
+func messageArgs(message utils.Message) ([]interface{}, error) {
+	var payload, headers []byte
+	if len(message.Payload) > 0 {
+		payload = message.Payload
+	}
+	if len(message.Headers) > 0 {
+		var err error
+		if headers, err = json.Marshal(message.Headers); err != nil {
+			return nil, err
+		}
+	}
+
+	return []interface{}{message.Content(), payload, message.Key, headers, message.GetContentType(), message.ExpiresAt, message.PriorityLevel(), message.RoutedFrom()}, nil
+}
+
+// scanMessageColumns заполняет структурные поля сообщения из колонок payload и headers.
+func scanMessageColumns(message *utils.Message, payload, headers []byte) error {
+	if len(payload) > 0 {
+		message.Payload = payload
+		message.Message = ""
+	}
+	if len(headers) > 0 {
+		return json.Unmarshal(headers, &message.Headers)
+	}
+	return nil
+}
+
 func (r *Repository) tableExists(ctx context.Context, tableName string) (bool, error) {
 	var exists bool
 	query := `SELECT EXISTS (
@@ -247,3 +502,20 @@ func (r *Repository) GetUser(ctx context.Context, username string) (api.User, er
 
 	return i, err
 }
+
+const updateUserRole = `-- name: UpdateUserRole :execrows
+UPDATE users SET role = $2
+WHERE username = $1
+`
+
+// UpdateUserRole меняет роль пользователя и возвращает pgx.ErrNoRows, если пользователя нет.
+func (r *Repository) UpdateUserRole(ctx context.Context, username, role string) error {
+	tag, err := r.db.Exec(ctx, updateUserRole, username, role)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
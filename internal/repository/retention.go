@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// RetentionParams - сообщения топика, удаляемые политикой хранения.
+type RetentionParams struct {
+	Topic           string
+	Before          time.Time // Удаляются сообщения, созданные раньше; нулевое значение не ограничивает возраст
+	KeepRows        int64     // Сколько последних сообщений оставить; 0 не ограничивает количество
+	KeepUnprocessed bool      // Не удалять необработанные сообщения, кроме истекших
+	Limit           int
+}
+
+// ArchiveFunc записывает удаляемые сообщения до их удаления и возвращает finish, которая вызывается после
+// завершения транзакции удаления: committed сообщает, зафиксировано ли удаление. Архив должен становиться
+// окончательным только при committed, иначе после отката сообщения попадут в архив повторно.
+type ArchiveFunc func([]StoredMessage) (finish func(committed bool) error, err error)
+
+// PurgeMessages удаляет до arg.Limit самых старых сообщений, нарушающих политику хранения, и возвращает их число.
+// Если archive не nil, сообщения сначала передаются ему, а при ошибке архивации не удаляются.
+// Блокировки SKIP LOCKED позволяют нескольким репликам удалять сообщения одновременно без повторной архивации.
+func (r *Repository) PurgeMessages(ctx context.Context, arg RetentionParams, archive ArchiveFunc) (int, error) {
+	var limits []string
+	var args []interface{}
+	if !arg.Before.IsZero() {
+		args = append(args, arg.Before)
+		limits = append(limits, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+	if arg.KeepRows > 0 {
+		args = append(args, arg.KeepRows)
+		limits = append(limits, fmt.Sprintf("id <= (SELECT id FROM %s ORDER BY id DESC OFFSET $%d LIMIT 1)", arg.Topic, len(args)))
+	}
+	if len(limits) == 0 {
+		return 0, nil
+	}
+	conditions := "(" + strings.Join(limits, " OR ") + ")"
+	if arg.KeepUnprocessed {
+		conditions += " AND (processed OR expired_at IS NOT NULL)"
+	}
+	args = append(args, arg.Limit)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := fmt.Sprintf(`SELECT %s FROM %s
+WHERE %s
+ORDER BY id
+LIMIT $%d
+FOR UPDATE SKIP LOCKED`, storedMessageColumns, arg.Topic, conditions, len(args))
+
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	var items []StoredMessage
+	for rows.Next() {
+		i, err := scanStoredMessage(rows, arg.Topic)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		items = append(items, i)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(items) == 0 {
+		return 0, nil
+	}
+
+	return deleteArchived(ctx, tx, arg.Topic, items, archive)
+}
+
+// PurgeExpiredMessages удаляет до limit сообщений топика, срок жизни которых истек раньше before,
+// вместе с их арендами консьюмеров и возвращает число удаленных сообщений.
+// Если archive не nil, сообщения сначала передаются ему, как в PurgeMessages.
+func (r *Repository) PurgeExpiredMessages(ctx context.Context, topic string, before time.Time, limit int, archive ArchiveFunc) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := fmt.Sprintf(`SELECT %s FROM %s
+WHERE expires_at < $1
+ORDER BY expires_at
+LIMIT $2
+FOR UPDATE SKIP LOCKED`, storedMessageColumns, topic)
+
+	rows, err := tx.Query(ctx, query, before, limit)
+	if err != nil {
+		return 0, err
+	}
+	var items []StoredMessage
+	for rows.Next() {
+		i, err := scanStoredMessage(rows, topic)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		items = append(items, i)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(items) == 0 {
+		return 0, nil
+	}
+
+	return deleteArchived(ctx, tx, topic, items, archive)
+}
+
+// deleteArchived передает сообщения archive, удаляет их вместе с арендами консьюмеров и фиксирует tx.
+// Архив завершается после фиксации или отката удаления.
+func deleteArchived(ctx context.Context, tx pgx.Tx, topic string, items []StoredMessage, archive ArchiveFunc) (int, error) {
+	finish := func(bool) error { return nil }
+	if archive != nil {
+		var err error
+		if finish, err = archive(items); err != nil {
+			return 0, err
+		}
+	}
+
+	ids := make([]int, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	query := fmt.Sprintf(`WITH deleted AS (
+    DELETE FROM %s WHERE id = ANY($1) RETURNING id
+)
+DELETE FROM consumer_leases WHERE topic = $2 AND message_id IN (SELECT id FROM deleted)`, topic)
+	if _, err := tx.Exec(ctx, query, ids, topic); err != nil {
+		_ = finish(false)
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		_ = finish(false)
+		return 0, err
+	}
+	return len(items), finish(true)
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"ProjectMessageService/internal/utils"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPurgeExpiredMessagesArchive(t *testing.T) {
+	r := newTestRepository(t)
+	ctx := context.Background()
+
+	expired := time.Now().Add(-time.Hour)
+	alive := time.Now().Add(time.Hour)
+	require.NoError(t, r.SaveMessage(ctx, utils.Message{Topic: testTopic, Message: "expired", ExpiresAt: &expired}))
+	require.NoError(t, r.SaveMessage(ctx, utils.Message{Topic: testTopic, Message: "alive", ExpiresAt: &alive}))
+
+	// Ошибка архивации оставляет сообщения в базе
+	_, err := r.PurgeExpiredMessages(ctx, testTopic, time.Now(), 10, func([]StoredMessage) (func(bool) error, error) {
+		return nil, errors.New("disk full")
+	})
+	require.Error(t, err)
+
+	var archived []StoredMessage
+	var committed []bool
+	deleted, err := r.PurgeExpiredMessages(ctx, testTopic, time.Now(), 10, func(messages []StoredMessage) (func(bool) error, error) {
+		archived = messages
+		return func(ok bool) error {
+			committed = append(committed, ok)
+			return nil
+		}, nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, deleted)
+	require.Len(t, archived, 1)
+	require.Equal(t, "expired", archived[0].Message.Message)
+	require.Equal(t, []bool{true}, committed)
+
+	deleted, err = r.PurgeExpiredMessages(ctx, testTopic, time.Now(), 10, nil)
+	require.NoError(t, err)
+	require.Zero(t, deleted)
+}
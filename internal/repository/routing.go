@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"ProjectMessageService/internal/routing"
+	"context"
+	"encoding/json"
+
+	"github.com/jackc/pgx/v4"
+)
+
+const createRoutingRule = `-- name: CreateRoutingRule :one
+INSERT INTO routing_rules (
+    topic,
+    name,
+    priority,
+    conditions,
+    action,
+    target_topic,
+    is_active
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+) RETURNING id, topic, name, priority, conditions, action, target_topic, is_active, created_at
+`
+
+type CreateRoutingRuleParams struct {
+	Topic       string              `json:"topic"`
+	Name        string              `json:"name"`
+	Priority    int                 `json:"priority"`
+	Conditions  []routing.Condition `json:"conditions"`
+	Action      string              `json:"action"`
+	TargetTopic string              `json:"target_topic"`
+	IsActive    bool                `json:"is_active"`
+}
+
+func (r *Repository) CreateRoutingRule(ctx context.Context, arg CreateRoutingRuleParams) (routing.Rule, error) {
+	conditions, err := json.Marshal(arg.Conditions)
+	if err != nil {
+		return routing.Rule{}, err
+	}
+	row := r.db.QueryRow(ctx, createRoutingRule,
+		arg.Topic,
+		arg.Name,
+		arg.Priority,
+		conditions,
+		arg.Action,
+		arg.TargetTopic,
+		arg.IsActive,
+	)
+	return scanRoutingRule(row)
+}
+
+const listRoutingRules = `-- name: ListRoutingRules :many
+SELECT id, topic, name, priority, conditions, action, target_topic, is_active, created_at FROM routing_rules
+WHERE $1 = '' OR topic = $1
+ORDER BY topic, priority, id
+`
+
+// ListRoutingRules возвращает правила топика или, если topic пустой, все правила.
+func (r *Repository) ListRoutingRules(ctx context.Context, topic string) ([]routing.Rule, error) {
+	rows, err := r.db.Query(ctx, listRoutingRules, topic)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []routing.Rule{}
+	for rows.Next() {
+		i, err := scanRoutingRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	return items, rows.Err()
+}
+
+const setRoutingRuleActive = `-- name: SetRoutingRuleActive :one
+UPDATE routing_rules SET is_active = $2 WHERE id = $1
+RETURNING id, topic, name, priority, conditions, action, target_topic, is_active, created_at
+`
+
+// SetRoutingRuleActive включает или отключает правило и возвращает pgx.ErrNoRows, если его нет.
+func (r *Repository) SetRoutingRuleActive(ctx context.Context, id int64, isActive bool) (routing.Rule, error) {
+	return scanRoutingRule(r.db.QueryRow(ctx, setRoutingRuleActive, id, isActive))
+}
+
+const deleteRoutingRule = `-- name: DeleteRoutingRule :exec
+DELETE FROM routing_rules WHERE id = $1
+`
+
+// DeleteRoutingRule удаляет правило и возвращает pgx.ErrNoRows, если его нет.
+func (r *Repository) DeleteRoutingRule(ctx context.Context, id int64) error {
+	tag, err := r.db.Exec(ctx, deleteRoutingRule, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func scanRoutingRule(row pgx.Row) (routing.Rule, error) {
+	var i routing.Rule
+	var conditions []byte
+	err := row.Scan(
+		&i.ID,
+		&i.Topic,
+		&i.Name,
+		&i.Priority,
+		&conditions,
+		&i.Action,
+		&i.TargetTopic,
+		&i.IsActive,
+		&i.CreatedAt,
+	)
+	if err != nil {
+		return i, err
+	}
+	err = json.Unmarshal(conditions, &i.Conditions)
+	return i, err
+}
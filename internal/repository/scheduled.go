@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"ProjectMessageService/internal/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// ErrAlreadyDelivered возвращается при отмене отложенного сообщения, которое уже опубликовано.
+var ErrAlreadyDelivered = errors.New("scheduled message is already delivered")
+
+// ErrDeliveryInProgress возвращается при отмене отложенного сообщения, которое сейчас публикует планировщик.
+var ErrDeliveryInProgress = errors.New("scheduled message is being delivered")
+
+// ScheduledMessage - сообщение, ожидающее отложенной доставки.
+type ScheduledMessage struct {
+	ID          int64         `json:"id"`
+	Topic       string        `json:"topic"`
+	Message     utils.Message `json:"message"`
+	DeliverAt   time.Time     `json:"deliver_at"`
+	CreatedBy   string        `json:"created_by"`
+	CreatedAt   time.Time     `json:"created_at"`
+	DeliveredAt *time.Time    `json:"delivered_at"`
+}
+
+const createScheduledMessage = `-- name: CreateScheduledMessage :one
+INSERT INTO scheduled_messages (
+    topic,
+    message,
+    deliver_at,
+    created_by
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING id, topic, message, deliver_at, created_by, created_at, delivered_at
+`
+
+type CreateScheduledMessageParams struct {
+	Message   utils.Message `json:"message"`
+	DeliverAt time.Time     `json:"deliver_at"`
+	CreatedBy string        `json:"created_by"`
+}
+
+func (r *Repository) CreateScheduledMessage(ctx context.Context, arg CreateScheduledMessageParams) (ScheduledMessage, error) {
+	args, err := scheduledMessageArgs(arg)
+	if err != nil {
+		return ScheduledMessage{}, err
+	}
+	return scanScheduledMessage(r.db.QueryRow(ctx, createScheduledMessage, args...))
+}
+
+// scheduledMessageArgs возвращает параметры запроса createScheduledMessage.
+func scheduledMessageArgs(arg CreateScheduledMessageParams) ([]interface{}, error) {
+	message, err := json.Marshal(arg.Message.Immediate())
+	if err != nil {
+		return nil, err
+	}
+	return []interface{}{arg.Message.Topic, message, arg.DeliverAt, arg.CreatedBy}, nil
+}
+
+const getScheduledMessage = `-- name: GetScheduledMessage :one
+SELECT id, topic, message, deliver_at, created_by, created_at, delivered_at FROM scheduled_messages
+WHERE id = $1
+`
+
+func (r *Repository) GetScheduledMessage(ctx context.Context, id int64) (ScheduledMessage, error) {
+	return scanScheduledMessage(r.db.QueryRow(ctx, getScheduledMessage, id))
+}
+
+const deleteScheduledMessage = `-- name: DeleteScheduledMessage :exec
+DELETE FROM scheduled_messages WHERE id = $1 AND delivered_at IS NULL
+AND (claimed_until IS NULL OR claimed_until < now())
+`
+
+// CancelScheduledMessage удаляет еще не опубликованное сообщение. Возвращает pgx.ErrNoRows, если сообщения нет,
+// ErrAlreadyDelivered, если оно уже опубликовано, и ErrDeliveryInProgress, если его сейчас публикует планировщик.
+func (r *Repository) CancelScheduledMessage(ctx context.Context, id int64) error {
+	tag, err := r.db.Exec(ctx, deleteScheduledMessage, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() > 0 {
+		return nil
+	}
+
+	message, err := r.GetScheduledMessage(ctx, id)
+	if err != nil {
+		return err
+	}
+	if message.DeliveredAt == nil {
+		return ErrDeliveryInProgress
+	}
+	return ErrAlreadyDelivered
+}
+
+const claimDueScheduledMessages = `-- name: ClaimDueScheduledMessages :many
+UPDATE scheduled_messages SET claimed_until = now() + $2::interval
+WHERE id IN (
+    SELECT id FROM scheduled_messages
+    WHERE delivered_at IS NULL AND deliver_at <= now()
+    AND (claimed_until IS NULL OR claimed_until < now())
+    ORDER BY deliver_at, id
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, topic, message, deliver_at, created_by, created_at, delivered_at
+`
+
+const markScheduledMessagesDelivered = `-- name: MarkScheduledMessagesDelivered :exec
+UPDATE scheduled_messages SET delivered_at = now(), claimed_until = NULL WHERE id = ANY($1)
+`
+
+const releaseScheduledMessages = `-- name: ReleaseScheduledMessages :exec
+UPDATE scheduled_messages SET claimed_until = NULL WHERE id = ANY($1) AND delivered_at IS NULL
+`
+
+// DeliverDueScheduledMessages захватывает на время claim до limit сообщений, время доставки которых наступило,
+// и передает их deliver. Захват фиксируется до вызова deliver, поэтому строки не остаются заблокированными
+// на время публикации, а другие реплики пропускают захваченные сообщения. Если deliver завершился без ошибки,
+// сообщения отмечаются доставленными, иначе захват снимается. Если процесс остановился во время публикации,
+// сообщения будут опубликованы повторно по истечении claim.
+func (r *Repository) DeliverDueScheduledMessages(ctx context.Context, limit int, claim time.Duration, deliver func(context.Context, []ScheduledMessage) error) (int, error) {
+	rows, err := r.db.Query(ctx, claimDueScheduledMessages, limit, claim)
+	if err != nil {
+		return 0, err
+	}
+	var items []ScheduledMessage
+	for rows.Next() {
+		i, err := scanScheduledMessage(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		items = append(items, i)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(items) == 0 {
+		return 0, nil
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].DeliverAt.Equal(items[j].DeliverAt) {
+			return items[i].DeliverAt.Before(items[j].DeliverAt)
+		}
+		return items[i].ID < items[j].ID
+	})
+
+	ids := make([]int64, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	// Отметки выполняются и после отмены ctx, чтобы результат публикации не был потерян
+	if err = deliver(ctx, items); err != nil {
+		if _, releaseErr := r.db.Exec(context.Background(), releaseScheduledMessages, ids); releaseErr != nil {
+			r.app.Log.Errorf("Не удалось снять захват отложенных сообщений: %v", releaseErr)
+		}
+		return 0, err
+	}
+	if _, err = r.db.Exec(context.Background(), markScheduledMessagesDelivered, ids); err != nil {
+		return 0, err
+	}
+	return len(items), nil
+}
+
+func scanScheduledMessage(row pgx.Row) (ScheduledMessage, error) {
+	var i ScheduledMessage
+	var message []byte
+	err := row.Scan(
+		&i.ID,
+		&i.Topic,
+		&message,
+		&i.DeliverAt,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.DeliveredAt,
+	)
+	if err != nil {
+		return i, err
+	}
+	err = json.Unmarshal(message, &i.Message)
+	return i, err
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"ProjectMessageService/internal/utils"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeliverDueScheduledMessages(t *testing.T) {
+	r := newTestRepository(t)
+	ctx := context.Background()
+	_, err := r.db.Exec(ctx, `DELETE FROM scheduled_messages WHERE topic = $1`, testTopic)
+	require.NoError(t, err)
+
+	due, err := r.CreateScheduledMessage(ctx, CreateScheduledMessageParams{
+		Message: utils.Message{Topic: testTopic, Message: "due"}, DeliverAt: time.Now().Add(-time.Second), CreatedBy: "alice",
+	})
+	require.NoError(t, err)
+	later, err := r.CreateScheduledMessage(ctx, CreateScheduledMessageParams{
+		Message: utils.Message{Topic: testTopic, Message: "later"}, DeliverAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	// Во время публикации сообщение захвачено: его нельзя отменить и не публикует другая реплика
+	failed := errors.New("kafka unavailable")
+	_, err = r.DeliverDueScheduledMessages(ctx, 10, time.Minute, func(ctx context.Context, items []ScheduledMessage) error {
+		require.Len(t, items, 1)
+		require.Equal(t, due.ID, items[0].ID)
+		require.ErrorIs(t, r.CancelScheduledMessage(ctx, due.ID), ErrDeliveryInProgress)
+
+		delivered, err := r.DeliverDueScheduledMessages(ctx, 10, time.Minute, func(context.Context, []ScheduledMessage) error {
+			t.Fatal("claimed message delivered twice")
+			return nil
+		})
+		require.NoError(t, err)
+		require.Zero(t, delivered)
+		return failed
+	})
+	require.ErrorIs(t, err, failed)
+
+	// После ошибки захват снят, сообщение публикуется следующей попыткой
+	delivered, err := r.DeliverDueScheduledMessages(ctx, 10, time.Minute, func(context.Context, []ScheduledMessage) error { return nil })
+	require.NoError(t, err)
+	require.Equal(t, 1, delivered)
+	require.ErrorIs(t, r.CancelScheduledMessage(ctx, due.ID), ErrAlreadyDelivered)
+
+	require.NoError(t, r.CancelScheduledMessage(ctx, later.ID))
+}
+
+func TestSaveMessageBatchWithScheduled(t *testing.T) {
+	r := newTestRepository(t)
+	ctx := context.Background()
+
+	saved, scheduled, err := r.SaveMessageBatch(ctx,
+		[]utils.Message{{Topic: testTopic, Message: "now"}},
+		[]CreateScheduledMessageParams{{Message: utils.Message{Topic: testTopic, Message: "later"}, DeliverAt: time.Now().Add(time.Hour)}})
+	require.NoError(t, err)
+	require.Len(t, saved, 1)
+	require.True(t, saved[0].Inserted)
+	require.Len(t, scheduled, 1)
+	require.Equal(t, "later", scheduled[0].Message.Message)
+
+	// Ошибка любого сообщения отменяет всю транзакцию, в том числе отложенные сообщения
+	_, _, err = r.SaveMessageBatch(ctx,
+		[]utils.Message{{Topic: testTopic, Message: "rolled back"}, {Topic: "missing_topic", Message: "fails"}},
+		[]CreateScheduledMessageParams{{Message: utils.Message{Topic: testTopic, Message: "rolled back later"}, DeliverAt: time.Now().Add(time.Hour)}})
+	require.Error(t, err)
+
+	var count int
+	require.NoError(t, r.db.QueryRow(ctx, `SELECT COUNT(*) FROM `+testTopic+` WHERE content = 'rolled back'`).Scan(&count))
+	require.Zero(t, count)
+	require.NoError(t, r.db.QueryRow(ctx, `SELECT COUNT(*) FROM scheduled_messages WHERE message->>'message' = 'rolled back later'`).Scan(&count))
+	require.Zero(t, count)
+}
+
+func TestSaveMessagesDuplicates(t *testing.T) {
+	r := newTestRepository(t)
+	ctx := context.Background()
+	require.NoError(t, r.SaveMessage(ctx, utils.Message{Topic: testTopic, Message: "existing"}))
+
+	// Повтор содержимого в пакете и в таблице не создает новых строк
+	saved, err := r.SaveMessages(ctx, []utils.Message{
+		{Topic: testTopic, Message: "new"},
+		{Topic: testTopic, Message: "existing"},
+		{Topic: testTopic, Message: "new"},
+	})
+	require.NoError(t, err)
+	require.Len(t, saved, 3)
+	require.True(t, saved[0].Inserted)
+	require.False(t, saved[1].Inserted)
+	require.False(t, saved[2].Inserted)
+	require.Equal(t, saved[0].ID, saved[2].ID)
+
+	var count int
+	require.NoError(t, r.db.QueryRow(ctx, `SELECT COUNT(*) FROM `+testTopic).Scan(&count))
+	require.Equal(t, 2, count)
+}
+
+func TestSaveRoutedCopies(t *testing.T) {
+	r := newTestRepository(t)
+	ctx := context.Background()
+	require.NoError(t, r.SaveMessage(ctx, utils.Message{Topic: testTopic, Message: "routed"}))
+
+	// Копии разных исходных сообщений не считаются дублями существующего сообщения с тем же телом,
+	// повтор копии того же сообщения - дубль
+	copies := []utils.Message{
+		{Topic: testTopic, Message: "routed", Headers: map[string]string{utils.RoutedFromHeader: "orders/1"}},
+		{Topic: testTopic, Message: "routed", Headers: map[string]string{utils.RoutedFromHeader: "orders/2"}},
+		{Topic: testTopic, Message: "routed", Headers: map[string]string{utils.RoutedFromHeader: "orders/1"}},
+	}
+	saved, err := r.SaveMessages(ctx, copies)
+	require.NoError(t, err)
+	require.True(t, saved[0].Inserted)
+	require.True(t, saved[1].Inserted)
+	require.False(t, saved[2].Inserted)
+	require.Equal(t, saved[0].ID, saved[2].ID)
+
+	key, err := r.ContentMessagesKey(ctx, copies[1])
+	require.NoError(t, err)
+	require.Equal(t, saved[1].ID, key)
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+type TopicSchema struct {
+	Topic         string          `json:"topic"`
+	Version       int             `json:"version"`
+	Schema        json.RawMessage `json:"schema"`
+	Compatibility string          `json:"compatibility"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+const createTopicSchema = `-- name: CreateTopicSchema :one
+INSERT INTO topic_schemas (
+    topic,
+    version,
+    schema,
+    compatibility
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING topic, version, schema, compatibility, created_at
+`
+
+type CreateTopicSchemaParams struct {
+	Topic         string          `json:"topic"`
+	Version       int             `json:"version"`
+	Schema        json.RawMessage `json:"schema"`
+	Compatibility string          `json:"compatibility"`
+}
+
+// CreateTopicSchema сохраняет новую версию схемы. Версия должна быть на единицу больше последней,
+// при одновременной регистрации одна из вставок завершится нарушением первичного ключа.
+func (r *Repository) CreateTopicSchema(ctx context.Context, arg CreateTopicSchemaParams) (TopicSchema, error) {
+	row := r.db.QueryRow(ctx, createTopicSchema,
+		arg.Topic,
+		arg.Version,
+		[]byte(arg.Schema),
+		arg.Compatibility,
+	)
+	var i TopicSchema
+	var schema []byte
+	err := row.Scan(
+		&i.Topic,
+		&i.Version,
+		&schema,
+		&i.Compatibility,
+		&i.CreatedAt,
+	)
+	i.Schema = schema
+	return i, err
+}
+
+const getLatestTopicSchema = `-- name: GetLatestTopicSchema :one
+SELECT topic, version, schema, compatibility, created_at FROM topic_schemas
+WHERE topic = $1
+ORDER BY version DESC
+LIMIT 1
+`
+
+func (r *Repository) GetLatestTopicSchema(ctx context.Context, topic string) (TopicSchema, error) {
+	row := r.db.QueryRow(ctx, getLatestTopicSchema, topic)
+	var i TopicSchema
+	var schema []byte
+	err := row.Scan(
+		&i.Topic,
+		&i.Version,
+		&schema,
+		&i.Compatibility,
+		&i.CreatedAt,
+	)
+	i.Schema = schema
+	return i, err
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Размеры интервалов временного ряда статистики, совпадают с полями date_trunc.
+const (
+	BucketMinute = "minute"
+	BucketHour   = "hour"
+	BucketDay    = "day"
+)
+
+// BucketDuration возвращает длительность интервала или 0 для неизвестного размера.
+func BucketDuration(bucket string) time.Duration {
+	switch bucket {
+	case BucketMinute:
+		return time.Minute
+	case BucketHour:
+		return time.Hour
+	case BucketDay:
+		return 24 * time.Hour
+	}
+	return 0
+}
+
+// StatsParams - окно статистики [From, To) и размер интервала временного ряда.
+type StatsParams struct {
+	Topic  string
+	From   time.Time
+	To     time.Time
+	Bucket string
+}
+
+// TopicStats - статистика сообщений топика, опубликованных в окне.
+type TopicStats struct {
+	Topic       string `json:"topic"`
+	TableExists bool   `json:"table_exists"`
+	Total       int    `json:"total"`
+	Processed   int    `json:"processed"`
+	Pending     int    `json:"pending"`
+	Failed      int    `json:"failed"`
+	Expired     int    `json:"expired"` // Пропущены консьюмером из-за истечения срока жизни
+	// Обработано в окне по времени обработки, в среднем за минуту и за час
+	ThroughputPerMinute float64 `json:"throughput_per_minute"`
+	ThroughputPerHour   float64 `json:"throughput_per_hour"`
+	// Задержка от публикации до обработки в секундах, null - если обработанных сообщений нет
+	AvgLatencySeconds *float64      `json:"avg_latency_seconds"`
+	P95LatencySeconds *float64      `json:"p95_latency_seconds"`
+	Series            []StatsBucket `json:"series"`
+}
+
+// StatsBucket - число опубликованных, обработанных, неудачно обработанных и истекших сообщений за интервал.
+type StatsBucket struct {
+	Start     time.Time `json:"start"`
+	Published int       `json:"published"`
+	Processed int       `json:"processed"`
+	Failed    int       `json:"failed"`
+	Expired   int       `json:"expired"`
+}
+
+// GetTopicStats считает статистику топика за окно arg. Если таблицы нет, возвращает нули.
+func (r *Repository) GetTopicStats(ctx context.Context, arg StatsParams) (TopicStats, error) {
+	stats := TopicStats{Topic: arg.Topic, Series: []StatsBucket{}}
+	exists, err := r.tableExists(ctx, arg.Topic)
+	if err != nil || !exists {
+		return stats, err
+	}
+	stats.TableExists = true
+
+	query := fmt.Sprintf(`SELECT
+	COUNT(*) FILTER (WHERE created_at >= $1 AND created_at < $2),
+	COUNT(*) FILTER (WHERE created_at >= $1 AND created_at < $2 AND processed),
+	COUNT(*) FILTER (WHERE created_at >= $1 AND created_at < $2 AND processed IS NOT TRUE AND failed_at IS NULL AND expired_at IS NULL),
+	COUNT(*) FILTER (WHERE created_at >= $1 AND created_at < $2 AND processed IS NOT TRUE AND failed_at IS NOT NULL AND expired_at IS NULL),
+	COUNT(*) FILTER (WHERE created_at >= $1 AND created_at < $2 AND processed IS NOT TRUE AND expired_at IS NOT NULL),
+	COUNT(*) FILTER (WHERE processed_at >= $1 AND processed_at < $2),
+	(AVG(EXTRACT(EPOCH FROM processed_at - created_at)) FILTER (WHERE created_at >= $1 AND created_at < $2))::float8,
+	percentile_cont(0.95) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM processed_at - created_at))
+		FILTER (WHERE created_at >= $1 AND created_at < $2 AND processed_at IS NOT NULL)
+FROM %s
+WHERE created_at >= $1 AND created_at < $2 OR processed_at >= $1 AND processed_at < $2`, arg.Topic)
+
+	var processedInWindow int
+	err = r.db.QueryRow(ctx, query, arg.From, arg.To).Scan(&stats.Total, &stats.Processed, &stats.Pending, &stats.Failed,
+		&stats.Expired, &processedInWindow, &stats.AvgLatencySeconds, &stats.P95LatencySeconds)
+	if err != nil {
+		return stats, err
+	}
+	if window := arg.To.Sub(arg.From); window > 0 {
+		stats.ThroughputPerMinute = float64(processedInWindow) / window.Minutes()
+		stats.ThroughputPerHour = float64(processedInWindow) / window.Hours()
+	}
+
+	stats.Series, err = r.statsSeries(ctx, arg)
+	return stats, err
+}
+
+// statsSeries строит временной ряд по интервалам arg.Bucket, включая интервалы без событий.
+func (r *Repository) statsSeries(ctx context.Context, arg StatsParams) ([]StatsBucket, error) {
+	query := fmt.Sprintf(`WITH events AS (
+	SELECT date_trunc($1::text, created_at) AS bucket, 1 AS published, 0 AS processed, 0 AS failed, 0 AS expired
+	FROM %[1]s WHERE created_at >= $2 AND created_at < $3
+	UNION ALL
+	SELECT date_trunc($1::text, processed_at), 0, 1, 0, 0
+	FROM %[1]s WHERE processed_at >= $2 AND processed_at < $3
+	UNION ALL
+	SELECT date_trunc($1::text, failed_at), 0, 0, 1, 0
+	FROM %[1]s WHERE failed_at >= $2 AND failed_at < $3
+	UNION ALL
+	SELECT date_trunc($1::text, expired_at), 0, 0, 0, 1
+	FROM %[1]s WHERE expired_at >= $2 AND expired_at < $3
+)
+SELECT b.start, COALESCE(SUM(e.published), 0), COALESCE(SUM(e.processed), 0), COALESCE(SUM(e.failed), 0), COALESCE(SUM(e.expired), 0)
+FROM generate_series(date_trunc($1::text, $2::timestamptz), $3::timestamptz - interval '1 microsecond', ('1 ' || $1::text)::interval) AS b(start)
+LEFT JOIN events e ON e.bucket = b.start
+GROUP BY b.start
+ORDER BY b.start`, arg.Topic)
+
+	rows, err := r.db.Query(ctx, query, arg.Bucket, arg.From, arg.To)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []StatsBucket{}
+	for rows.Next() {
+		var i StatsBucket
+		if err = rows.Scan(&i.Start, &i.Published, &i.Processed, &i.Failed, &i.Expired); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	return items, rows.Err()
+}
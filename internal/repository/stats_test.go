@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"ProjectMessageService/internal/utils"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTopicStats(t *testing.T) {
+	r := newTestRepository(t)
+	ctx := context.Background()
+
+	ids := make(map[string]int)
+	for _, content := range []string{"processed", "failed", "expired", "pending"} {
+		message := utils.Message{Topic: testTopic, Message: content}
+		require.NoError(t, r.SaveMessage(ctx, message))
+		id, err := r.ContentMessagesKey(ctx, message)
+		require.NoError(t, err)
+		ids[content] = id
+	}
+	require.NoError(t, r.MarkMessageAsProcessed(ctx, utils.Message{Topic: testTopic, Message: "processed"}, ids["processed"]))
+	require.NoError(t, r.MarkMessageAsFailed(ctx, testTopic, ids["failed"], errors.New("boom")))
+	require.NoError(t, r.MarkMessageAsExpired(ctx, testTopic, ids["expired"]))
+
+	now := time.Now()
+	stats, err := r.GetTopicStats(ctx, StatsParams{Topic: testTopic, From: now.Add(-time.Hour), To: now.Add(time.Hour), Bucket: BucketHour})
+	require.NoError(t, err)
+	require.True(t, stats.TableExists)
+	require.Equal(t, 4, stats.Total)
+	require.Equal(t, 1, stats.Processed)
+	require.Equal(t, 1, stats.Pending)
+	require.Equal(t, 1, stats.Failed)
+	require.Equal(t, 1, stats.Expired)
+	require.NotNil(t, stats.AvgLatencySeconds)
+	require.InDelta(t, 0.5, stats.ThroughputPerHour, 1e-9)
+
+	// Ряд покрывает окно без пропусков, события попадают в свои интервалы
+	require.GreaterOrEqual(t, len(stats.Series), 2)
+	var total StatsBucket
+	for _, bucket := range stats.Series {
+		total.Published += bucket.Published
+		total.Processed += bucket.Processed
+		total.Failed += bucket.Failed
+		total.Expired += bucket.Expired
+	}
+	require.Equal(t, StatsBucket{Published: 4, Processed: 1, Failed: 1, Expired: 1}, total)
+
+	// Окно без сообщений
+	stats, err = r.GetTopicStats(ctx, StatsParams{Topic: testTopic, From: now.Add(-48 * time.Hour), To: now.Add(-24 * time.Hour), Bucket: BucketDay})
+	require.NoError(t, err)
+	require.Zero(t, stats.Total)
+	require.Nil(t, stats.AvgLatencySeconds)
+	require.Len(t, stats.Series, 2)
+
+	stats, err = r.GetTopicStats(ctx, StatsParams{Topic: "repotest_missing", From: now.Add(-time.Hour), To: now, Bucket: BucketHour})
+	require.NoError(t, err)
+	require.False(t, stats.TableExists)
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+)
+
+// MessageCounts - количество сообщений в таблице топика.
+type MessageCounts struct {
+	TableExists bool `json:"table_exists"`
+	Total       int  `json:"total"`
+	Processed   int  `json:"processed"`
+}
+
+// CountMessages считает все и обработанные сообщения топика. Если таблицы нет, возвращает нули.
+func (r *Repository) CountMessages(ctx context.Context, topic string) (MessageCounts, error) {
+	var counts MessageCounts
+	exists, err := r.tableExists(ctx, topic)
+	if err != nil || !exists {
+		return counts, err
+	}
+	counts.TableExists = true
+
+	query := fmt.Sprintf(`SELECT COUNT(*), COUNT(*) FILTER (WHERE processed) FROM %s`, topic)
+	err = r.db.QueryRow(ctx, query).Scan(&counts.Total, &counts.Processed)
+	return counts, err
+}
@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+type Webhook struct {
+	ID           int64     `json:"id"`
+	Topic        string    `json:"topic"`
+	URL          string    `json:"url"`
+	Secret       string    `json:"-"`
+	Filter       string    `json:"filter"`
+	IsActive     bool      `json:"is_active"`
+	FailureCount int       `json:"failure_count"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+const createWebhook = `-- name: CreateWebhook :one
+INSERT INTO webhooks (
+    topic,
+    url,
+    secret,
+    filter
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING id, topic, url, secret, filter, is_active, failure_count, created_at
+`
+
+type CreateWebhookParams struct {
+	Topic  string `json:"topic"`
+	URL    string `json:"url"`
+	Secret string `json:"secret"`
+	Filter string `json:"filter"`
+}
+
+func (r *Repository) CreateWebhook(ctx context.Context, arg CreateWebhookParams) (Webhook, error) {
+	row := r.db.QueryRow(ctx, createWebhook,
+		arg.Topic,
+		arg.URL,
+		arg.Secret,
+		arg.Filter,
+	)
+	var i Webhook
+	err := row.Scan(
+		&i.ID,
+		&i.Topic,
+		&i.URL,
+		&i.Secret,
+		&i.Filter,
+		&i.IsActive,
+		&i.FailureCount,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listActiveWebhooks = `-- name: ListActiveWebhooks :many
+SELECT id, topic, url, secret, filter, is_active, failure_count, created_at FROM webhooks
+WHERE topic = $1 AND is_active = true
+ORDER BY id
+`
+
+func (r *Repository) ListActiveWebhooks(ctx context.Context, topic string) ([]Webhook, error) {
+	rows, err := r.db.Query(ctx, listActiveWebhooks, topic)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []Webhook
+	for rows.Next() {
+		var i Webhook
+		if err = rows.Scan(
+			&i.ID,
+			&i.Topic,
+			&i.URL,
+			&i.Secret,
+			&i.Filter,
+			&i.IsActive,
+			&i.FailureCount,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	return items, rows.Err()
+}
+
+const createWebhookDelivery = `-- name: CreateWebhookDelivery :exec
+INSERT INTO webhook_deliveries (
+    webhook_id,
+    message_id,
+    attempt,
+    status_code,
+    error,
+    success
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+)
+`
+
+type CreateWebhookDeliveryParams struct {
+	WebhookID  int64  `json:"webhook_id"`
+	MessageID  int    `json:"message_id"`
+	Attempt    int    `json:"attempt"`
+	StatusCode int    `json:"status_code"`
+	Error      string `json:"error"`
+	Success    bool   `json:"success"`
+}
+
+func (r *Repository) CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) error {
+	_, err := r.db.Exec(ctx, createWebhookDelivery,
+		arg.WebhookID,
+		arg.MessageID,
+		arg.Attempt,
+		arg.StatusCode,
+		arg.Error,
+		arg.Success,
+	)
+	return err
+}
+
+const markWebhookSucceeded = `-- name: MarkWebhookSucceeded :exec
+UPDATE webhooks SET failure_count = 0 WHERE id = $1
+`
+
+// MarkWebhookSucceeded сбрасывает счетчик неудачных доставок.
+func (r *Repository) MarkWebhookSucceeded(ctx context.Context, id int64) error {
+	_, err := r.db.Exec(ctx, markWebhookSucceeded, id)
+	return err
+}
+
+const markWebhookFailed = `-- name: MarkWebhookFailed :one
+UPDATE webhooks
+SET failure_count = failure_count + 1,
+    is_active = failure_count + 1 < $2
+WHERE id = $1
+RETURNING is_active
+`
+
+// MarkWebhookFailed увеличивает счетчик неудачных доставок и отключает вебхук,
+// если счетчик достиг maxFailures. Возвращает признак активности вебхука.
+func (r *Repository) MarkWebhookFailed(ctx context.Context, id int64, maxFailures int) (bool, error) {
+	var active bool
+	err := r.db.QueryRow(ctx, markWebhookFailed, id, maxFailures).Scan(&active)
+	return active, err
+}
@@ -0,0 +1,280 @@
+// Package routing вычисляет правила маршрутизации сообщений между топиками по их содержимому.
+package routing
+
+import (
+	"ProjectMessageService/internal/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Действия правила.
+const (
+	ActionForward = "forward" // Переслать в TargetTopic вместо доставки подписчикам исходного топика
+	ActionCopy    = "copy"    // Скопировать в TargetTopic и продолжить обработку
+	ActionDrop    = "drop"    // Не доставлять сообщение подписчикам
+)
+
+// Типы условий.
+const (
+	ConditionHeader   = "header"    // Заголовок Key равен Equals или соответствует Matches
+	ConditionJSONPath = "json_path" // Значение payload по пути Key (user.tier, items[0].sku) равно Equals или соответствует Matches
+	ConditionContent  = "content"   // Тело сообщения соответствует регулярному выражению Matches
+)
+
+// Condition - условие правила. Задается ровно одно из Equals и Matches, для content - только Matches.
+type Condition struct {
+	Type    string  `json:"type"`
+	Key     string  `json:"key,omitempty"`
+	Equals  *string `json:"equals,omitempty"`
+	Matches string  `json:"matches,omitempty"`
+}
+
+// Rule - правило маршрутизации сообщений топика Topic. Правило срабатывает, если выполнены все условия.
+type Rule struct {
+	ID          int64       `json:"id"`
+	Topic       string      `json:"topic"`
+	Name        string      `json:"name"`
+	Priority    int         `json:"priority"` // Правила проверяются по возрастанию Priority, затем ID
+	Conditions  []Condition `json:"conditions"`
+	Action      string      `json:"action"`
+	TargetTopic string      `json:"target_topic,omitempty"`
+	IsActive    bool        `json:"is_active"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+// Match - сработавшее правило.
+type Match struct {
+	RuleID      int64  `json:"rule_id"`
+	Name        string `json:"name"`
+	Action      string `json:"action"`
+	TargetTopic string `json:"target_topic,omitempty"`
+}
+
+// Result - итог проверки правил для сообщения.
+type Result struct {
+	Deliver bool     `json:"deliver"` // false - сообщение переслано или отброшено
+	Targets []string `json:"targets"` // Топики, в которые нужно опубликовать сообщение
+	Matched []Match  `json:"matched"`
+}
+
+// Validate проверяет действие и условия правила.
+func (r Rule) Validate() error {
+	switch r.Action {
+	case ActionForward, ActionCopy:
+		if r.TargetTopic == "" {
+			return fmt.Errorf("action %s requires target_topic", r.Action)
+		}
+		if r.TargetTopic == r.Topic {
+			return errors.New("target_topic must differ from topic")
+		}
+	case ActionDrop:
+		if r.TargetTopic != "" {
+			return errors.New("action drop does not use target_topic")
+		}
+	default:
+		return fmt.Errorf("action must be one of %s, %s, %s", ActionForward, ActionCopy, ActionDrop)
+	}
+
+	for i, condition := range r.Conditions {
+		if err := condition.Validate(); err != nil {
+			return fmt.Errorf("conditions[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Validate проверяет тип, ключ и регулярное выражение условия.
+func (c Condition) Validate() error {
+	switch c.Type {
+	case ConditionHeader, ConditionJSONPath:
+		if c.Key == "" {
+			return fmt.Errorf("condition %s requires key", c.Type)
+		}
+		if (c.Equals == nil) == (c.Matches == "") {
+			return errors.New("exactly one of equals and matches is required")
+		}
+	case ConditionContent:
+		if c.Matches == "" || c.Equals != nil || c.Key != "" {
+			return errors.New("condition content requires only matches")
+		}
+	default:
+		return fmt.Errorf("type must be one of %s, %s, %s", ConditionHeader, ConditionJSONPath, ConditionContent)
+	}
+
+	if c.Matches != "" {
+		if _, err := compile(c.Matches); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RuleSet - правила топика с регулярными выражениями условий, скомпилированными при загрузке правил.
+// Выражения живут вместе с набором и освобождаются, когда набор заменяется после изменения правил.
+type RuleSet struct {
+	rules   []Rule
+	regexps map[string]*regexp.Regexp
+}
+
+// Compile компилирует регулярные выражения условий правил.
+func Compile(rules []Rule) (*RuleSet, error) {
+	set := &RuleSet{rules: rules, regexps: make(map[string]*regexp.Regexp)}
+	for _, rule := range rules {
+		for _, condition := range rule.Conditions {
+			if condition.Matches == "" {
+				continue
+			}
+			if _, ok := set.regexps[condition.Matches]; ok {
+				continue
+			}
+			re, err := compile(condition.Matches)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d: %w", rule.ID, err)
+			}
+			set.regexps[condition.Matches] = re
+		}
+	}
+	return set, nil
+}
+
+// Evaluate компилирует правила и проверяет по ним сообщение, как RuleSet.Evaluate.
+func Evaluate(rules []Rule, message utils.Message) (Result, error) {
+	set, err := Compile(rules)
+	if err != nil {
+		return Result{Deliver: true, Targets: []string{}, Matched: []Match{}}, err
+	}
+	return set.Evaluate(message)
+}
+
+// Evaluate проверяет активные правила по порядку. Правила copy накапливаются,
+// первое сработавшее правило forward или drop завершает проверку.
+func (s *RuleSet) Evaluate(message utils.Message) (Result, error) {
+	result := Result{Deliver: true, Targets: []string{}, Matched: []Match{}}
+	for _, rule := range s.rules {
+		if !rule.IsActive {
+			continue
+		}
+		ok, err := s.match(rule, message)
+		if err != nil {
+			return result, fmt.Errorf("rule %d: %w", rule.ID, err)
+		}
+		if !ok {
+			continue
+		}
+
+		result.Matched = append(result.Matched, Match{RuleID: rule.ID, Name: rule.Name, Action: rule.Action, TargetTopic: rule.TargetTopic})
+		switch rule.Action {
+		case ActionCopy:
+			result.Targets = append(result.Targets, rule.TargetTopic)
+		case ActionForward:
+			result.Targets = append(result.Targets, rule.TargetTopic)
+			result.Deliver = false
+			return result, nil
+		case ActionDrop:
+			result.Deliver = false
+			return result, nil
+		}
+	}
+	return result, nil
+}
+
+// match сообщает, выполнены ли все условия правила. Правило без условий срабатывает всегда.
+func (s *RuleSet) match(rule Rule, message utils.Message) (bool, error) {
+	for _, condition := range rule.Conditions {
+		ok, err := s.matchCondition(condition, message)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// matchCondition проверяет условие для сообщения. Отсутствующий заголовок или путь не выполняют условие.
+func (s *RuleSet) matchCondition(c Condition, message utils.Message) (bool, error) {
+	var value string
+	switch c.Type {
+	case ConditionHeader:
+		header, ok := message.Headers[c.Key]
+		if !ok {
+			return false, nil
+		}
+		value = header
+	case ConditionJSONPath:
+		found, ok := lookupJSONPath(message.Payload, c.Key)
+		if !ok {
+			return false, nil
+		}
+		value = found
+	case ConditionContent:
+		value = message.Content()
+	default:
+		return false, fmt.Errorf("unknown condition type %q", c.Type)
+	}
+
+	if c.Equals != nil {
+		return value == *c.Equals, nil
+	}
+	re, ok := s.regexps[c.Matches]
+	if !ok {
+		return false, fmt.Errorf("regular expression %q is not compiled", c.Matches)
+	}
+	return re.MatchString(value), nil
+}
+
+// lookupJSONPath возвращает значение payload по пути вида user.tier, $.items[0].sku или items.0.sku.
+// Строки возвращаются без кавычек, остальные значения - в виде JSON.
+func lookupJSONPath(payload json.RawMessage, path string) (string, bool) {
+	if len(payload) == 0 {
+		return "", false
+	}
+	var node interface{}
+	if err := json.Unmarshal(payload, &node); err != nil {
+		return "", false
+	}
+
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	path = strings.NewReplacer("[", ".", "]", "").Replace(path)
+	for _, part := range strings.Split(path, ".") {
+		if part == "" {
+			continue
+		}
+		switch current := node.(type) {
+		case map[string]interface{}:
+			next, ok := current[part]
+			if !ok {
+				return "", false
+			}
+			node = next
+		case []interface{}:
+			index, err := strconv.Atoi(part)
+			if err != nil || index < 0 || index >= len(current) {
+				return "", false
+			}
+			node = current[index]
+		default:
+			return "", false
+		}
+	}
+
+	if s, ok := node.(string); ok {
+		return s, true
+	}
+	encoded, err := json.Marshal(node)
+	if err != nil {
+		return "", false
+	}
+	return string(encoded), true
+}
+
+func compile(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression %q: %w", pattern, err)
+	}
+	return re, nil
+}
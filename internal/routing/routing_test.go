@@ -0,0 +1,79 @@
+package routing
+
+import (
+	"ProjectMessageService/internal/utils"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluate(t *testing.T) {
+	gold := "gold"
+	billing := "billing"
+	rules := []Rule{
+		{ID: 1, Topic: "message", Action: ActionCopy, TargetTopic: "audit", IsActive: true,
+			Conditions: []Condition{{Type: ConditionHeader, Key: "source", Equals: &billing}}},
+		{ID: 2, Topic: "message", Action: ActionForward, TargetTopic: "vip", IsActive: true,
+			Conditions: []Condition{{Type: ConditionJSONPath, Key: "$.user.tier", Equals: &gold}}},
+		{ID: 3, Topic: "message", Action: ActionDrop, IsActive: true,
+			Conditions: []Condition{{Type: ConditionContent, Matches: `(?i)spam`}}},
+	}
+
+	message := utils.Message{
+		Topic:   "message",
+		Payload: json.RawMessage(`{"user":{"tier":"gold"},"items":[{"sku":"A1"}]}`),
+		Headers: map[string]string{"source": "billing"},
+	}
+	result, err := Evaluate(rules, message)
+	require.NoError(t, err)
+	require.False(t, result.Deliver)
+	require.Equal(t, []string{"audit", "vip"}, result.Targets)
+	require.Len(t, result.Matched, 2)
+
+	result, err = Evaluate(rules, utils.Message{Topic: "message", Message: "Buy SPAM now"})
+	require.NoError(t, err)
+	require.False(t, result.Deliver)
+	require.Empty(t, result.Targets)
+
+	result, err = Evaluate(rules, utils.Message{Topic: "message", Message: "hello"})
+	require.NoError(t, err)
+	require.True(t, result.Deliver)
+	require.Empty(t, result.Matched)
+}
+
+func TestLookupJSONPath(t *testing.T) {
+	payload := json.RawMessage(`{"items":[{"sku":"A1","qty":2}],"ok":true}`)
+
+	value, ok := lookupJSONPath(payload, "items[0].sku")
+	require.True(t, ok)
+	require.Equal(t, "A1", value)
+
+	value, ok = lookupJSONPath(payload, "items.0.qty")
+	require.True(t, ok)
+	require.Equal(t, "2", value)
+
+	_, ok = lookupJSONPath(payload, "items[3].sku")
+	require.False(t, ok)
+}
+
+func TestRuleValidate(t *testing.T) {
+	require.NoError(t, Rule{Topic: "message", Action: ActionDrop}.Validate())
+	require.Error(t, Rule{Topic: "message", Action: ActionForward}.Validate())
+	require.Error(t, Rule{Topic: "message", Action: ActionCopy, TargetTopic: "message"}.Validate())
+	require.Error(t, Rule{Topic: "message", Action: ActionDrop,
+		Conditions: []Condition{{Type: ConditionContent, Matches: "("}}}.Validate())
+}
+
+func TestCompile(t *testing.T) {
+	_, err := Compile([]Rule{{ID: 7, Action: ActionDrop, Conditions: []Condition{{Type: ConditionContent, Matches: `(`}}}})
+	require.ErrorContains(t, err, "rule 7")
+
+	// Одинаковые выражения разных правил компилируются один раз
+	set, err := Compile([]Rule{
+		{ID: 1, Action: ActionDrop, Conditions: []Condition{{Type: ConditionContent, Matches: `^a`}}},
+		{ID: 2, Action: ActionDrop, Conditions: []Condition{{Type: ConditionContent, Matches: `^a`}}},
+	})
+	require.NoError(t, err)
+	require.Len(t, set.regexps, 1)
+}
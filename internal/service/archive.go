@@ -0,0 +1,85 @@
+package service
+
+import (
+	"ProjectMessageService/internal/repository"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// stagedArchive - архив сообщений, записанный во временный файл и еще не опубликованный.
+type stagedArchive struct {
+	temp string
+	path string
+}
+
+// stageArchive записывает сообщения по одному JSON-документу в строке во временный сжатый файл рядом с
+// <dir>/<topic>/<topic>-<время>-<первый id>-<последний id>.ndjson.gz. Файл получает окончательное имя
+// в finish, когда удаление сообщений из базы зафиксировано.
+func stageArchive(dir, topic string, messages []repository.StoredMessage) (*stagedArchive, error) {
+	topicDir := filepath.Join(dir, topic)
+	if err := os.MkdirAll(topicDir, 0o755); err != nil {
+		return nil, err
+	}
+	name := fmt.Sprintf("%s-%s-%d-%d.ndjson.gz", topic, time.Now().UTC().Format("20060102T150405.000000000Z"),
+		messages[0].ID, messages[len(messages)-1].ID)
+	path := filepath.Join(topicDir, name)
+
+	file, err := os.CreateTemp(topicDir, name+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	if err = writeArchive(file, messages); err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return nil, fmt.Errorf("cannot write archive %s: %w", path, err)
+	}
+	if err = file.Close(); err != nil {
+		_ = os.Remove(file.Name())
+		return nil, err
+	}
+	return &stagedArchive{temp: file.Name(), path: path}, nil
+}
+
+// finish публикует архив, если удаление сообщений зафиксировано, иначе удаляет временный файл.
+// Если опубликовать не удалось, временный файл остается: удаленные сообщения есть только в нем.
+func (a *stagedArchive) finish(committed bool) error {
+	if !committed {
+		return os.Remove(a.temp)
+	}
+	if err := os.Rename(a.temp, a.path); err != nil {
+		return fmt.Errorf("cannot publish archive %s: %w", a.temp, err)
+	}
+	return nil
+}
+
+// archiveFunc возвращает хук архивации удаляемых сообщений топика в каталог dir, nil - если dir не задан.
+func archiveFunc(dir, topic string) repository.ArchiveFunc {
+	if dir == "" {
+		return nil
+	}
+	return func(messages []repository.StoredMessage) (func(bool) error, error) {
+		archive, err := stageArchive(dir, topic, messages)
+		if err != nil {
+			return nil, err
+		}
+		return archive.finish, nil
+	}
+}
+
+func writeArchive(file *os.File, messages []repository.StoredMessage) error {
+	zw := gzip.NewWriter(file)
+	encoder := json.NewEncoder(zw)
+	for _, message := range messages {
+		if err := encoder.Encode(message); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return file.Sync()
+}
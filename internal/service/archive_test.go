@@ -0,0 +1,67 @@
+package service
+
+import (
+	"ProjectMessageService/internal/repository"
+	"ProjectMessageService/internal/utils"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveMessages(t *testing.T) {
+	dir := t.TempDir()
+	messages := []repository.StoredMessage{
+		{ID: 7, Message: utils.Message{Topic: "ping", Message: "first"}, Processed: true},
+		{ID: 9, Message: utils.Message{Topic: "ping", Payload: json.RawMessage(`{"n":2}`)}},
+	}
+
+	// Пока удаление не зафиксировано, архив остается временным файлом
+	finish, err := archiveFunc(dir, "ping")(messages)
+	require.NoError(t, err)
+	matches, err := filepath.Glob(filepath.Join(dir, "ping", "*.ndjson.gz"))
+	require.NoError(t, err)
+	require.Empty(t, matches)
+
+	require.NoError(t, finish(true))
+	matches, err = filepath.Glob(filepath.Join(dir, "ping", "*"))
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	require.Regexp(t, `^ping-\d{8}T\d{6}\.\d{9}Z-7-9\.ndjson\.gz$`, filepath.Base(matches[0]))
+
+	file, err := os.Open(matches[0])
+	require.NoError(t, err)
+	defer file.Close()
+	zr, err := gzip.NewReader(file)
+	require.NoError(t, err)
+
+	var got []repository.StoredMessage
+	scanner := bufio.NewScanner(zr)
+	for scanner.Scan() {
+		var message repository.StoredMessage
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &message))
+		got = append(got, message)
+	}
+	require.NoError(t, scanner.Err())
+	require.Len(t, got, 2)
+	require.Equal(t, "first", got[0].Message.Message)
+	require.True(t, got[0].Processed)
+	require.JSONEq(t, `{"n":2}`, string(got[1].Payload))
+}
+
+func TestArchiveDiscardedOnRollback(t *testing.T) {
+	dir := t.TempDir()
+	finish, err := archiveFunc(dir, "ping")([]repository.StoredMessage{{ID: 1}})
+	require.NoError(t, err)
+	require.NoError(t, finish(false))
+
+	entries, err := os.ReadDir(filepath.Join(dir, "ping"))
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	require.Nil(t, archiveFunc("", "ping"))
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"ProjectMessageService/internal/repository"
+	"context"
+	"sync"
+	"time"
+)
+
+// consumePollInterval - как часто long-poll перепроверяет базу, если уведомлений не было
+// (например, сообщение обработала другая реплика).
+const consumePollInterval = time.Second
+
+// topicNotifier будит ожидающих long-poll клиентов, когда в топике появляются обработанные сообщения.
+type topicNotifier struct {
+	mu      sync.Mutex
+	waiters map[string]chan struct{}
+	stopped chan struct{} // закрывается при остановке сервиса
+	stop    sync.Once
+}
+
+func newTopicNotifier() *topicNotifier {
+	return &topicNotifier{waiters: make(map[string]chan struct{}), stopped: make(chan struct{})}
+}
+
+// close завершает ожидание всех текущих и будущих long-poll запросов.
+func (n *topicNotifier) close() {
+	n.stop.Do(func() { close(n.stopped) })
+}
+
+// wait возвращает канал, который закроется при следующем notify для топика.
+func (n *topicNotifier) wait(topic string) <-chan struct{} {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	ch, ok := n.waiters[topic]
+	if !ok {
+		ch = make(chan struct{})
+		n.waiters[topic] = ch
+	}
+	return ch
+}
+
+func (n *topicNotifier) notify(topic string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if ch, ok := n.waiters[topic]; ok {
+		close(ch)
+		delete(n.waiters, topic)
+	}
+}
+
+// ConsumeBatch ждет до wait неподтвержденные сообщения для именованного консьюмера
+// и выдает их в аренду на leaseDuration.
+func (s *MessageService) ConsumeBatch(ctx context.Context, topic, consumer string, limit int, wait, leaseDuration time.Duration) ([]repository.LeasedMessage, error) {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	ticker := time.NewTicker(consumePollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Подписываемся до запроса, чтобы не пропустить уведомление между запросом и ожиданием
+		notified := s.notifier.wait(topic)
+
+		messages, err := s.repo.ClaimMessages(ctx, topic, consumer, limit, leaseDuration)
+		if err != nil {
+			s.app.Log.WithCtx(ctx).Errorf("Не удалось выдать сообщения консьюмеру %s топика %s: %v", consumer, topic, err)
+			return nil, err
+		}
+		if len(messages) > 0 || wait <= 0 {
+			return messages, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, nil
+		case <-s.notifier.stopped:
+			return nil, nil
+		case <-timer.C:
+			return nil, nil
+		case <-notified:
+		case <-ticker.C:
+		}
+	}
+}
+
+// StopConsumeWaits досрочно завершает long-poll запросы, чтобы HTTP-сервер мог остановиться,
+// не дожидаясь истечения wait_seconds.
+func (s *MessageService) StopConsumeWaits() {
+	s.notifier.close()
+}
+
+func (s *MessageService) AckMessages(ctx context.Context, topic, consumer string, leaseIDs []string) (int64, error) {
+	return s.repo.AckLeases(ctx, topic, consumer, leaseIDs)
+}
+
+func (s *MessageService) NackMessages(ctx context.Context, topic, consumer string, leaseIDs []string) (int64, error) {
+	return s.repo.NackLeases(ctx, topic, consumer, leaseIDs)
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopicNotifier(t *testing.T) {
+	n := newTopicNotifier()
+
+	first := n.wait("orders")
+	require.Equal(t, first, n.wait("orders"))
+	other := n.wait("payments")
+
+	// Уведомление будит только ожидающих своего топика, следующее ожидание получает новый канал
+	n.notify("orders")
+	require.True(t, closed(first))
+	require.False(t, closed(other))
+	require.False(t, closed(n.wait("orders")))
+
+	n.close()
+	n.close()
+	require.True(t, closed(n.stopped))
+}
+
+func closed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"ProjectMessageService/config"
+	"ProjectMessageService/internal/metrics"
+	"ProjectMessageService/internal/repository"
+	"ProjectMessageService/internal/utils"
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	expiryPurgeInterval  = time.Minute // Как часто удаляются истекшие сообщения
+	expiryPurgeBatchSize = 1000        // Сколько сообщений удаляется одним запросом
+
+	expiresAtHeader = "x-expires-at" // срок жизни сообщения в RFC3339
+)
+
+// topicTTLs - срок жизни сообщений топиков из MESSAGE_TTL.
+type topicTTLs struct {
+	mu   sync.RWMutex
+	ttls map[string]time.Duration
+}
+
+// SetTopicTTLs заменяет сроки жизни сообщений топиков, в том числе после перезагрузки конфигурации.
+// Новые значения применяются к сообщениям, опубликованным после изменения.
+func (s *MessageService) SetTopicTTLs(ttls map[string]time.Duration) {
+	s.ttls.mu.Lock()
+	defer s.ttls.mu.Unlock()
+	s.ttls.ttls = ttls
+}
+
+// withExpiry вычисляет срок жизни публикуемого сообщения: заданный в сообщении или TTL его топика.
+func (s *MessageService) withExpiry(message utils.Message, now time.Time) utils.Message {
+	s.ttls.mu.RLock()
+	ttl := s.ttls.ttls[message.Topic]
+	s.ttls.mu.RUnlock()
+	return message.WithExpiry(now, ttl)
+}
+
+// RunExpiryPurge удаляет из базы данных сообщения, срок жизни которых истек больше purgeDelay назад,
+// до отмены ctx. Задержка оставляет истекшие сообщения в статистике за последний период.
+// Если задан archiveDir, удаляемые сообщения архивируются так же, как при очистке по политикам хранения.
+func (s *MessageService) RunExpiryPurge(ctx context.Context, purgeDelay time.Duration, archiveDir string) {
+	ticker := time.NewTicker(expiryPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		before := time.Now().Add(-purgeDelay)
+		for _, topic := range config.MessageTypes() {
+			s.purgeExpired(ctx, topic, before, archiveFunc(archiveDir, topic))
+		}
+	}
+}
+
+// purgeExpired удаляет истекшие сообщения топика пачками, чтобы не блокировать таблицу надолго.
+func (s *MessageService) purgeExpired(ctx context.Context, topic string, before time.Time, archive repository.ArchiveFunc) {
+	total := 0
+	for ctx.Err() == nil {
+		deleted, err := s.repo.PurgeExpiredMessages(ctx, topic, before, expiryPurgeBatchSize, archive)
+		if err != nil {
+			if ctx.Err() == nil {
+				s.app.Log.Errorf("Не удалось удалить истекшие сообщения топика %s: %v", topic, err)
+			}
+			break
+		}
+		total += deleted
+		metrics.MessagesPurged(topic, metrics.PurgeExpired, int64(deleted))
+		if deleted < expiryPurgeBatchSize {
+			break
+		}
+	}
+	if total > 0 {
+		s.app.Log.Infof("Удалено %d истекших сообщений топика %s", total, topic)
+	}
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"ProjectMessageService/internal/utils"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Состояния горутины консьюмера топика.
+const (
+	ConsumerRunning = "running"
+	ConsumerStopped = "stopped"
+)
+
+// Результаты проверок готовности.
+const (
+	CheckOK   = "ok"
+	CheckFail = "fail"
+)
+
+// ConsumerStatus - состояние консьюмера одного топика Kafka. У типов с уровнями приоритета (PRIORITIES)
+// состояние ведется для топика каждого уровня.
+type ConsumerStatus struct {
+	MessageType   string     `json:"message_type"`
+	Priority      string     `json:"priority"`
+	Topic         string     `json:"topic"`
+	Group         string     `json:"group"`
+	State         string     `json:"state"`
+	StartedAt     time.Time  `json:"started_at"`
+	Partition     int        `json:"partition"`
+	LastOffset    int64      `json:"last_offset"` // -1, если сообщений еще не было
+	LastMessageAt *time.Time `json:"last_message_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastErrorAt   *time.Time `json:"last_error_at,omitempty"`
+}
+
+// HealthCheck - результат проверки одной зависимости сервиса.
+type HealthCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// consumerTracker хранит состояние горутин ConsumeMessages для проверок готовности и /status.
+// Состояния хранятся по топикам Kafka уровней приоритета.
+type consumerTracker struct {
+	mu        sync.RWMutex
+	started   bool
+	consumers map[string]*ConsumerStatus
+}
+
+func newConsumerTracker() *consumerTracker {
+	return &consumerTracker{consumers: make(map[string]*ConsumerStatus)}
+}
+
+// start отмечает консьюмер типа запущенным для топиков его уровней приоритета и возвращает false,
+// если он уже работает.
+func (t *consumerTracker) start(messageType string, levels []priorityLevel) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, status := range t.consumers {
+		if status.MessageType == messageType && status.State == ConsumerRunning {
+			return false
+		}
+	}
+	t.started = true
+	now := time.Now()
+	for _, level := range levels {
+		topic, group := priorityTopicAndGroup(messageType, level.priority)
+		t.consumers[topic] = &ConsumerStatus{
+			MessageType: messageType,
+			Priority:    level.priority,
+			Topic:       topic,
+			Group:       group,
+			State:       ConsumerRunning,
+			StartedAt:   now,
+			LastOffset:  -1,
+		}
+	}
+	return true
+}
+
+func (t *consumerTracker) received(msg kafka.Message) {
+	now := time.Now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if status, ok := t.consumers[msg.Topic]; ok {
+		status.Partition = msg.Partition
+		status.LastOffset = msg.Offset
+		status.LastMessageAt = &now
+	}
+}
+
+func (t *consumerTracker) failed(topic string, err error) {
+	now := time.Now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if status, ok := t.consumers[topic]; ok {
+		status.LastError = err.Error()
+		status.LastErrorAt = &now
+	}
+}
+
+// stop отмечает остановленными топики всех уровней типа.
+func (t *consumerTracker) stop(messageType string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, status := range t.consumers {
+		if status.MessageType == messageType {
+			status.State = ConsumerStopped
+		}
+	}
+}
+
+func (t *consumerTracker) statuses() []ConsumerStatus {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	result := make([]ConsumerStatus, 0, len(t.consumers))
+	for _, status := range t.consumers {
+		result = append(result, *status)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].MessageType != result[j].MessageType {
+			return result[i].MessageType < result[j].MessageType
+		}
+		return priorityRank(result[i].Priority) < priorityRank(result[j].Priority)
+	})
+	return result
+}
+
+func (t *consumerTracker) isStarted() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.started
+}
+
+// check возвращает ошибку, если хотя бы один из консьюмеров остановился.
+func (t *consumerTracker) check() error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	for _, topic := range sortedKeys(t.consumers) {
+		if status := t.consumers[topic]; status.State != ConsumerRunning {
+			return fmt.Errorf("consumer of topic %s is %s: %s", status.Topic, status.State, status.LastError)
+		}
+	}
+	return nil
+}
+
+// priorityRank возвращает место уровня приоритета в порядке обработки.
+func priorityRank(priority string) int {
+	for i, level := range utils.Priorities {
+		if level == priority {
+			return i
+		}
+	}
+	return len(utils.Priorities)
+}
+
+// ConsumerStatuses возвращает состояние консьюмеров всех топиков.
+func (s *MessageService) ConsumerStatuses() []ConsumerStatus {
+	return s.consumers.statuses()
+}
+
+// Readiness проверяет Postgres, доступность брокера Kafka и работу всех консьюмеров.
+// Второе значение - true, если все проверки пройдены.
+func (s *MessageService) Readiness(ctx context.Context) ([]HealthCheck, bool) {
+	checks := []HealthCheck{
+		newHealthCheck("postgres", s.repo.Ping(ctx)),
+		newHealthCheck("kafka", s.pingKafka(ctx)),
+	}
+	// Процесс, запущенный без консьюмеров (serve без -consumers), их не проверяет
+	if s.consumers.isStarted() {
+		checks = append(checks, newHealthCheck("consumers", s.consumers.check()))
+	}
+
+	ready := true
+	for _, check := range checks {
+		if check.Status != CheckOK {
+			ready = false
+		}
+	}
+	return checks, ready
+}
+
+// pingKafka запрашивает метаданные кластера у брокеров писателя.
+func (s *MessageService) pingKafka(ctx context.Context) error {
+	client := &kafka.Client{Addr: s.kafkaWriter.Addr}
+	_, err := client.Metadata(ctx, &kafka.MetadataRequest{})
+	return err
+}
+
+func newHealthCheck(name string, err error) HealthCheck {
+	if err != nil {
+		return HealthCheck{Name: name, Status: CheckFail, Error: err.Error()}
+	}
+	return HealthCheck{Name: name, Status: CheckOK}
+}
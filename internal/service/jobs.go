@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Состояния задачи повторной обработки.
+const (
+	JobRunning  = "running"
+	JobDone     = "done"
+	JobFailed   = "failed"
+	JobCanceled = "canceled"
+)
+
+// maxReprocessJobs - сколько последних задач повторной обработки хранится в памяти.
+const maxReprocessJobs = 100
+
+// ReprocessJob - фоновая задача повторной обработки сообщений топика.
+type ReprocessJob struct {
+	ID          int64      `json:"id"`
+	Topic       string     `json:"topic"`
+	Status      string     `json:"status"`
+	Republished int        `json:"republished"`
+	Error       string     `json:"error,omitempty"`
+	StartedAt   time.Time  `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+}
+
+// reprocessJobs хранит задачи повторной обработки процесса. Задачи выполняются до завершения
+// или остановки сервиса (stop), состояние теряется при перезапуске.
+type reprocessJobs struct {
+	mu     sync.Mutex
+	nextID int64
+	jobs   map[int64]*ReprocessJob
+	order  []int64
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newReprocessJobs() *reprocessJobs {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &reprocessJobs{jobs: make(map[int64]*ReprocessJob), ctx: ctx, cancel: cancel}
+}
+
+// start регистрирует задачу и выполняет run в фоне. run сообщает число обработанных сообщений через progress.
+func (j *reprocessJobs) start(topic string, run func(ctx context.Context, progress func(int)) (int, error)) ReprocessJob {
+	j.mu.Lock()
+	j.nextID++
+	job := &ReprocessJob{ID: j.nextID, Topic: topic, Status: JobRunning, StartedAt: time.Now()}
+	j.jobs[job.ID] = job
+	j.order = append(j.order, job.ID)
+	j.trim()
+	started := *job
+	j.wg.Add(1)
+	j.mu.Unlock()
+
+	go func() {
+		defer j.wg.Done()
+		total, err := run(j.ctx, func(total int) {
+			j.mu.Lock()
+			defer j.mu.Unlock()
+			job.Republished = total
+		})
+
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		now := time.Now()
+		job.Republished = total
+		job.FinishedAt = &now
+		switch {
+		case err == nil:
+			job.Status = JobDone
+		case j.ctx.Err() != nil:
+			job.Status = JobCanceled
+			job.Error = err.Error()
+		default:
+			job.Status = JobFailed
+			job.Error = err.Error()
+		}
+	}()
+	return started
+}
+
+// trim удаляет самые старые завершенные задачи сверх maxReprocessJobs.
+func (j *reprocessJobs) trim() {
+	for i := 0; len(j.jobs) > maxReprocessJobs && i < len(j.order); {
+		id := j.order[i]
+		if j.jobs[id].Status == JobRunning {
+			i++
+			continue
+		}
+		delete(j.jobs, id)
+		j.order = append(j.order[:i], j.order[i+1:]...)
+	}
+}
+
+func (j *reprocessJobs) get(id int64) (ReprocessJob, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	job, ok := j.jobs[id]
+	if !ok {
+		return ReprocessJob{}, false
+	}
+	return *job, true
+}
+
+// stop отменяет выполняющиеся задачи и ждет их завершения.
+func (j *reprocessJobs) stop() {
+	j.cancel()
+	j.wg.Wait()
+}
+
+// StartReprocess запускает ReprocessMessages в фоне и возвращает задачу. DryRun не поддерживается.
+func (s *MessageService) StartReprocess(arg ReprocessParams) ReprocessJob {
+	arg.DryRun = false
+	return s.jobs.start(arg.Filter.Topic, func(ctx context.Context, progress func(int)) (int, error) {
+		total, err := s.reprocessMessages(ctx, arg, progress)
+		if err != nil {
+			s.app.Log.Errorf("Повторная обработка сообщений топика %s прервана после %d сообщений: %v", arg.Filter.Topic, total, err)
+		}
+		return total, err
+	})
+}
+
+// ReprocessJob возвращает задачу повторной обработки по id.
+func (s *MessageService) ReprocessJob(id int64) (ReprocessJob, bool) {
+	return s.jobs.get(id)
+}
+
+// StopReprocessJobs отменяет фоновые задачи повторной обработки и ждет их завершения.
+func (s *MessageService) StopReprocessJobs() {
+	s.jobs.stop()
+}
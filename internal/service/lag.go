@@ -0,0 +1,194 @@
+package service
+
+import (
+	"ProjectMessageService/internal/utils"
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// PartitionLag - отставание группы консьюмера в одном разделе топика Kafka.
+type PartitionLag struct {
+	Partition       int    `json:"partition"`
+	CommittedOffset int64  `json:"committed_offset"` // -1, если группа еще не фиксировала смещение
+	LowWatermark    int64  `json:"low_watermark"`
+	HighWatermark   int64  `json:"high_watermark"`
+	Lag             int64  `json:"lag"`
+	Error           string `json:"error,omitempty"` // Смещения раздела не получены, отставание не учтено
+}
+
+// ConsumerLag - отставание консьюмера уровня приоритета типа сообщений в Kafka. Число необработанных
+// строк типа в базе общее для всех уровней, поэтому указывается только в записи уровня normal.
+type ConsumerLag struct {
+	MessageType string         `json:"message_type"`
+	Priority    string         `json:"priority"`
+	Topic       string         `json:"topic"`
+	Group       string         `json:"group"`
+	TotalLag    int64          `json:"total_lag"`
+	Partitions  []PartitionLag `json:"partitions"`
+	DBBacklog   *int           `json:"db_backlog,omitempty"`
+	Error       string         `json:"error,omitempty"`
+}
+
+// ConsumerLags возвращает отставание групп, которые использует NewKafkaReader, для перечисленных типов сообщений,
+// по одной записи на каждый уровень приоритета типа.
+// Ошибка возвращается, только если кластер Kafka недоступен; ошибки отдельных топиков попадают в поле Error,
+// ошибки отдельных разделов - в поле Error раздела.
+func (s *MessageService) ConsumerLags(ctx context.Context, messageTypes []string) ([]ConsumerLag, error) {
+	client := &kafka.Client{Addr: s.kafkaWriter.Addr}
+
+	topics := make([]string, 0, len(messageTypes))
+	for _, messageType := range messageTypes {
+		for _, level := range s.messagePriorities(messageType) {
+			topic, _ := priorityTopicAndGroup(messageType, level.priority)
+			topics = append(topics, topic)
+		}
+	}
+	metadata, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: topics})
+	if err != nil {
+		return nil, err
+	}
+	partitions := make(map[string][]int)
+	topicErrors := make(map[string]error)
+	for _, topic := range metadata.Topics {
+		if topic.Error != nil {
+			topicErrors[topic.Name] = topic.Error
+			continue
+		}
+		for _, partition := range topic.Partitions {
+			partitions[topic.Name] = append(partitions[topic.Name], partition.ID)
+		}
+	}
+
+	lags := make([]ConsumerLag, 0, len(topics))
+	for _, messageType := range messageTypes {
+		counts, err := s.repo.CountMessages(ctx, messageType)
+		if err != nil {
+			return nil, err
+		}
+		backlog := counts.Total - counts.Processed
+
+		for _, level := range s.messagePriorities(messageType) {
+			topic, group := priorityTopicAndGroup(messageType, level.priority)
+			lag := ConsumerLag{MessageType: messageType, Priority: level.priority, Topic: topic, Group: group, Partitions: []PartitionLag{}}
+			if level.priority == utils.PriorityNormal {
+				lag.DBBacklog = &backlog
+			}
+
+			switch {
+			case topicErrors[topic] != nil:
+				lag.Error = topicErrors[topic].Error()
+			case len(partitions[topic]) == 0:
+				lag.Error = fmt.Sprintf("topic %s has no partitions", topic)
+			default:
+				if err := partitionLags(ctx, client, &lag, partitions[topic]); err != nil {
+					lag.Error = err.Error()
+				}
+			}
+			lags = append(lags, lag)
+		}
+	}
+	return lags, nil
+}
+
+// partitionLags заполняет смещения и отставание группы lag по разделам топика. Ошибка возвращается, только если
+// не удалось выполнить запрос; ошибки отдельных разделов попадают в их поле Error.
+func partitionLags(ctx context.Context, client *kafka.Client, lag *ConsumerLag, partitions []int) error {
+	first, err := topicOffsets(ctx, client, lag.Topic, partitions, kafka.FirstOffsetOf)
+	if err != nil {
+		return err
+	}
+	last, err := topicOffsets(ctx, client, lag.Topic, partitions, kafka.LastOffsetOf)
+	if err != nil {
+		return err
+	}
+	committed, err := client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{
+		GroupID: lag.Group,
+		Topics:  map[string][]int{lag.Topic: partitions},
+	})
+	if err != nil {
+		return err
+	}
+	if committed.Error != nil {
+		return committed.Error
+	}
+
+	lag.Partitions, lag.TotalLag = computePartitionLags(partitions, first, last, committed.Topics[lag.Topic])
+	return nil
+}
+
+// topicOffsets запрашивает смещения разделов топика, не прерываясь на ошибках отдельных разделов.
+func topicOffsets(ctx context.Context, client *kafka.Client, topic string, partitions []int,
+	request func(partition int) kafka.OffsetRequest) ([]kafka.PartitionOffsets, error) {
+	requests := make([]kafka.OffsetRequest, len(partitions))
+	for i, partition := range partitions {
+		requests[i] = request(partition)
+	}
+	response, err := client.ListOffsets(ctx, &kafka.ListOffsetsRequest{
+		Topics: map[string][]kafka.OffsetRequest{topic: requests},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response.Topics[topic], nil
+}
+
+// computePartitionLags вычисляет отставание разделов и их сумму. Раздел, для которого брокер вернул ошибку
+// или не вернул смещения, получает Error и не учитывается в сумме.
+func computePartitionLags(partitions []int, first, last []kafka.PartitionOffsets, committed []kafka.OffsetFetchPartition) ([]PartitionLag, int64) {
+	firstByPartition := make(map[int]kafka.PartitionOffsets, len(first))
+	for _, offsets := range first {
+		firstByPartition[offsets.Partition] = offsets
+	}
+	lastByPartition := make(map[int]kafka.PartitionOffsets, len(last))
+	for _, offsets := range last {
+		lastByPartition[offsets.Partition] = offsets
+	}
+	committedByPartition := make(map[int]kafka.OffsetFetchPartition, len(committed))
+	for _, offsets := range committed {
+		committedByPartition[offsets.Partition] = offsets
+	}
+
+	result := make([]PartitionLag, 0, len(partitions))
+	var total int64
+	for _, partition := range partitions {
+		item := PartitionLag{Partition: partition, CommittedOffset: -1}
+		low, lowOK := firstByPartition[partition]
+		high, highOK := lastByPartition[partition]
+		group, groupOK := committedByPartition[partition]
+		switch {
+		case !lowOK || !highOK:
+			item.Error = "offsets not returned"
+		case low.Error != nil:
+			item.Error = low.Error.Error()
+		case high.Error != nil:
+			item.Error = high.Error.Error()
+		case groupOK && group.Error != nil:
+			item.Error = group.Error.Error()
+		}
+		if item.Error != "" {
+			result = append(result, item)
+			continue
+		}
+
+		item.LowWatermark = low.FirstOffset
+		item.HighWatermark = high.LastOffset
+		if groupOK {
+			item.CommittedOffset = group.CommittedOffset
+		}
+		// Без зафиксированного смещения читатель начинает с первого сообщения раздела (kafka.FirstOffset)
+		start := item.CommittedOffset
+		if start < item.LowWatermark {
+			start = item.LowWatermark
+		}
+		if item.HighWatermark > start {
+			item.Lag = item.HighWatermark - start
+		}
+		total += item.Lag
+		result = append(result, item)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Partition < result[j].Partition })
+	return result, total
+}
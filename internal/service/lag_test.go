@@ -0,0 +1,37 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputePartitionLags(t *testing.T) {
+	first := []kafka.PartitionOffsets{
+		{Partition: 0, FirstOffset: 10},
+		{Partition: 1, FirstOffset: 0},
+		{Partition: 2, Error: errors.New("not leader for partition")},
+	}
+	last := []kafka.PartitionOffsets{
+		{Partition: 0, LastOffset: 50},
+		{Partition: 1, LastOffset: 7},
+		{Partition: 2, LastOffset: 100},
+	}
+	committed := []kafka.OffsetFetchPartition{
+		{Partition: 0, CommittedOffset: 40},
+		{Partition: 1, CommittedOffset: -1},
+	}
+
+	lags, total := computePartitionLags([]int{3, 2, 1, 0}, first, last, committed)
+	require.Equal(t, []PartitionLag{
+		{Partition: 0, CommittedOffset: 40, LowWatermark: 10, HighWatermark: 50, Lag: 10},
+		// Без зафиксированного смещения отставание считается от начала раздела
+		{Partition: 1, CommittedOffset: -1, LowWatermark: 0, HighWatermark: 7, Lag: 7},
+		// Ошибка раздела не влияет на остальные
+		{Partition: 2, CommittedOffset: -1, Error: "not leader for partition"},
+		{Partition: 3, CommittedOffset: -1, Error: "offsets not returned"},
+	}, lags)
+	require.EqualValues(t, 17, total)
+}
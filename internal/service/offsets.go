@@ -0,0 +1,271 @@
+package service
+
+import (
+	"ProjectMessageService/internal/utils"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Позиции для сброса смещений группы консьюмера.
+const (
+	OffsetEarliest = "earliest"
+	OffsetLatest   = "latest"
+)
+
+// ErrConsumerGroupActive возвращается при сбросе смещений группы, у которой есть активные участники.
+var ErrConsumerGroupActive = errors.New("consumer group has active members")
+
+// ErrNoPriorityTopics возвращается при сбросе смещений уровня high или low типа без уровней приоритета.
+var ErrNoPriorityTopics = errors.New("message type has no priority topics")
+
+// ResetOffsetsParams - куда переместить смещения группы консьюмера типа сообщений.
+// Задается ровно одно из Position, Offset или Timestamp.
+type ResetOffsetsParams struct {
+	MessageType string
+	Position    string     // OffsetEarliest или OffsetLatest
+	Offset      *int64     // Одно смещение для всех разделов, ограничивается их границами
+	Timestamp   *time.Time // Первое сообщение не раньше указанного времени
+	Partition   *int       // Только один раздел, по умолчанию все
+	Priority    string     // Уровень приоритета, топик которого сбрасывается; по умолчанию normal
+	Reprocess   bool       // Разрешить повторную обработку сообщений базы, созданных не раньше Timestamp
+}
+
+// Validate проверяет, что задано ровно одно из Position, Offset и Timestamp.
+func (p ResetOffsetsParams) Validate() error {
+	targets := 0
+	if p.Position != "" {
+		if p.Position != OffsetEarliest && p.Position != OffsetLatest {
+			return fmt.Errorf("position must be %s or %s", OffsetEarliest, OffsetLatest)
+		}
+		targets++
+	}
+	if p.Offset != nil {
+		targets++
+	}
+	if p.Timestamp != nil {
+		targets++
+	}
+	if targets != 1 {
+		return errors.New("exactly one of position, offset and timestamp is required")
+	}
+	if p.Reprocess && p.Timestamp == nil {
+		return errors.New("reprocess requires timestamp")
+	}
+	switch p.Priority {
+	case "", utils.PriorityHigh, utils.PriorityNormal, utils.PriorityLow:
+	default:
+		return fmt.Errorf("priority must be %s, %s or %s", utils.PriorityHigh, utils.PriorityNormal, utils.PriorityLow)
+	}
+	return nil
+}
+
+// PartitionReset - смещение раздела до и после сброса.
+type PartitionReset struct {
+	Partition int   `json:"partition"`
+	Previous  int64 `json:"previous"` // -1, если группа еще не фиксировала смещение
+	Offset    int64 `json:"offset"`
+}
+
+// OffsetsReset - результат сброса смещений группы.
+type OffsetsReset struct {
+	Topic       string           `json:"topic"`
+	Group       string           `json:"group"`
+	Partitions  []PartitionReset `json:"partitions"`
+	Reprocessed int64            `json:"reprocessed"` // Сколько сообщений базы отмечено для повторной обработки
+}
+
+// ResetOffsets фиксирует новые смещения группы, которую использует NewKafkaReader для уровня arg.Priority,
+// чтобы консьюмеры перечитали (или пропустили) сообщения топика. Группа не должна иметь активных участников:
+// консьюмеры этого типа сообщений во всех процессах нужно остановить на время сброса.
+func (s *MessageService) ResetOffsets(ctx context.Context, arg ResetOffsetsParams) (OffsetsReset, error) {
+	topic, group := priorityTopicAndGroup(arg.MessageType, arg.Priority)
+	result := OffsetsReset{Topic: topic, Group: group}
+	if err := arg.Validate(); err != nil {
+		return result, err
+	}
+	if _, ok := s.priorities[arg.MessageType]; !ok && topic != KafkaTopic(arg.MessageType) {
+		return result, fmt.Errorf("%w: %s is not listed in PRIORITIES", ErrNoPriorityTopics, arg.MessageType)
+	}
+	client := &kafka.Client{Addr: s.kafkaWriter.Addr}
+
+	groups, err := client.DescribeGroups(ctx, &kafka.DescribeGroupsRequest{GroupIDs: []string{group}})
+	if err != nil {
+		return result, err
+	}
+	for _, g := range groups.Groups {
+		if g.Error != nil {
+			return result, g.Error
+		}
+		if len(g.Members) > 0 {
+			return result, fmt.Errorf("%w: %s is %s with %d members", ErrConsumerGroupActive, group, g.GroupState, len(g.Members))
+		}
+	}
+
+	partitions, err := topicPartitions(ctx, client, topic)
+	if err != nil {
+		return result, err
+	}
+	if arg.Partition != nil {
+		found := false
+		for _, partition := range partitions {
+			found = found || partition == *arg.Partition
+		}
+		if !found {
+			return result, fmt.Errorf("topic %s has no partition %d", topic, *arg.Partition)
+		}
+		partitions = []int{*arg.Partition}
+	}
+
+	first, err := listOffsets(ctx, client, topic, partitions, kafka.FirstOffsetOf)
+	if err != nil {
+		return result, err
+	}
+	last, err := listOffsets(ctx, client, topic, partitions, kafka.LastOffsetOf)
+	if err != nil {
+		return result, err
+	}
+	var byTime map[int]kafka.PartitionOffsets
+	if arg.Timestamp != nil {
+		byTime, err = listOffsets(ctx, client, topic, partitions, func(partition int) kafka.OffsetRequest {
+			return kafka.TimeOffsetOf(partition, *arg.Timestamp)
+		})
+		if err != nil {
+			return result, err
+		}
+	}
+	previous, err := committedOffsets(ctx, client, group, topic, partitions)
+	if err != nil {
+		return result, err
+	}
+
+	commits := make([]kafka.OffsetCommit, 0, len(partitions))
+	for _, partition := range partitions {
+		low, high := first[partition].FirstOffset, last[partition].LastOffset
+		var offset int64
+		switch {
+		case arg.Timestamp != nil:
+			// Если сообщений после Timestamp нет, брокер не возвращает смещение - читаем только новые
+			offset = high
+			for found := range byTime[partition].Offsets {
+				if found >= 0 {
+					offset = found
+				}
+			}
+		case arg.Offset != nil:
+			offset = *arg.Offset
+		case arg.Position == OffsetEarliest:
+			offset = low
+		default:
+			offset = high
+		}
+		if offset < low {
+			offset = low
+		}
+		if offset > high {
+			offset = high
+		}
+
+		commits = append(commits, kafka.OffsetCommit{Partition: partition, Offset: offset})
+		result.Partitions = append(result.Partitions, PartitionReset{Partition: partition, Previous: previous[partition], Offset: offset})
+	}
+
+	// Без участников группа принимает смещения вне поколения (GenerationID -1)
+	committed, err := client.OffsetCommit(ctx, &kafka.OffsetCommitRequest{
+		GroupID:      group,
+		GenerationID: -1,
+		Topics:       map[string][]kafka.OffsetCommit{topic: commits},
+	})
+	if err != nil {
+		return result, err
+	}
+	for _, partition := range committed.Topics[topic] {
+		if partition.Error != nil {
+			return result, fmt.Errorf("partition %d: %w", partition.Partition, partition.Error)
+		}
+	}
+	s.app.Log.WithCtx(ctx).Infof("Смещения группы %s топика %s сброшены: %v", group, topic, result.Partitions)
+
+	if arg.Reprocess {
+		result.Reprocessed, err = s.repo.RequestReprocessSince(ctx, arg.MessageType, *arg.Timestamp)
+		if err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// topicPartitions возвращает номера разделов топика Kafka.
+func topicPartitions(ctx context.Context, client *kafka.Client, topic string) ([]int, error) {
+	metadata, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{topic}})
+	if err != nil {
+		return nil, err
+	}
+	var partitions []int
+	for _, t := range metadata.Topics {
+		if t.Error != nil {
+			return nil, t.Error
+		}
+		for _, partition := range t.Partitions {
+			partitions = append(partitions, partition.ID)
+		}
+	}
+	if len(partitions) == 0 {
+		return nil, fmt.Errorf("topic %s has no partitions", topic)
+	}
+	return partitions, nil
+}
+
+// listOffsets запрашивает смещения разделов топика. Каждый раздел запрашивается один раз,
+// так как брокер отклоняет запрос с повторяющимися разделами.
+func listOffsets(ctx context.Context, client *kafka.Client, topic string, partitions []int,
+	request func(partition int) kafka.OffsetRequest) (map[int]kafka.PartitionOffsets, error) {
+	requests := make([]kafka.OffsetRequest, len(partitions))
+	for i, partition := range partitions {
+		requests[i] = request(partition)
+	}
+	response, err := client.ListOffsets(ctx, &kafka.ListOffsetsRequest{
+		Topics: map[string][]kafka.OffsetRequest{topic: requests},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	offsets := make(map[int]kafka.PartitionOffsets, len(partitions))
+	for _, partition := range response.Topics[topic] {
+		if partition.Error != nil {
+			return nil, fmt.Errorf("partition %d: %w", partition.Partition, partition.Error)
+		}
+		offsets[partition.Partition] = partition
+	}
+	return offsets, nil
+}
+
+// committedOffsets возвращает зафиксированные смещения группы, -1 для разделов без смещения.
+func committedOffsets(ctx context.Context, client *kafka.Client, group, topic string, partitions []int) (map[int]int64, error) {
+	response, err := client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{
+		GroupID: group,
+		Topics:  map[string][]int{topic: partitions},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if response.Error != nil {
+		return nil, response.Error
+	}
+
+	offsets := make(map[int]int64, len(partitions))
+	for _, partition := range partitions {
+		offsets[partition] = -1
+	}
+	for _, partition := range response.Topics[topic] {
+		if partition.Error != nil {
+			return nil, fmt.Errorf("partition %d: %w", partition.Partition, partition.Error)
+		}
+		offsets[partition.Partition] = partition.CommittedOffset
+	}
+	return offsets, nil
+}
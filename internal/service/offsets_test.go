@@ -0,0 +1,24 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResetOffsetsParamsValidate(t *testing.T) {
+	offset := int64(10)
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	require.NoError(t, ResetOffsetsParams{Position: OffsetEarliest}.Validate())
+	require.NoError(t, ResetOffsetsParams{Offset: &offset}.Validate())
+	require.NoError(t, ResetOffsetsParams{Timestamp: &at, Reprocess: true}.Validate())
+
+	require.Error(t, ResetOffsetsParams{}.Validate())
+	require.Error(t, ResetOffsetsParams{Position: "middle"}.Validate())
+	require.Error(t, ResetOffsetsParams{Position: OffsetLatest, Offset: &offset}.Validate())
+	require.Error(t, ResetOffsetsParams{Position: OffsetEarliest, Reprocess: true}.Validate())
+	require.NoError(t, ResetOffsetsParams{Position: OffsetEarliest, Priority: "high"}.Validate())
+	require.Error(t, ResetOffsetsParams{Position: OffsetEarliest, Priority: "urgent"}.Validate())
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"ProjectMessageService/internal/utils"
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// SetPriorityWeights задает типы сообщений с уровнями приоритета (PRIORITIES) и веса уровней.
+// Вызывается до ConsumeMessages и публикации сообщений.
+func (s *MessageService) SetPriorityWeights(priorities map[string]map[string]int) {
+	s.priorities = priorities
+}
+
+// messagePriorities возвращает уровни приоритета, которые читает консьюмер типа сообщений, с их весами.
+// У типа без уровней приоритета один уровень normal.
+func (s *MessageService) messagePriorities(messageType string) []priorityLevel {
+	weights, ok := s.priorities[messageType]
+	if !ok {
+		return []priorityLevel{{priority: utils.PriorityNormal, weight: 1}}
+	}
+	levels := make([]priorityLevel, 0, len(utils.Priorities))
+	for _, priority := range utils.Priorities {
+		levels = append(levels, priorityLevel{priority: priority, weight: weights[priority]})
+	}
+	return levels
+}
+
+// MissingPriorityTopics возвращает топики Kafka уровней high и low типов из PRIORITIES, которых нет в кластере.
+// Топики уровней создает topics create -priorities.
+func (s *MessageService) MissingPriorityTopics(ctx context.Context) ([]string, error) {
+	if len(s.priorities) == 0 {
+		return nil, nil
+	}
+	client := &kafka.Client{Addr: s.kafkaWriter.Addr}
+	// Запрос без списка топиков не создает отсутствующие топики при auto.create.topics.enable
+	metadata, err := client.Metadata(ctx, &kafka.MetadataRequest{})
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]bool, len(metadata.Topics))
+	for _, topic := range metadata.Topics {
+		if topic.Error == nil {
+			existing[topic.Name] = true
+		}
+	}
+	return missingPriorityTopics(s.priorities, existing), nil
+}
+
+func missingPriorityTopics(priorities map[string]map[string]int, existing map[string]bool) []string {
+	var missing []string
+	for messageType := range priorities {
+		for _, priority := range []string{utils.PriorityHigh, utils.PriorityLow} {
+			if topic, _ := priorityTopicAndGroup(messageType, priority); !existing[topic] {
+				missing = append(missing, topic)
+			}
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// kafkaMessage формирует запись Kafka; сообщения типов с уровнями приоритета публикуются в топик своего уровня.
+func (s *MessageService) kafkaMessage(message utils.Message) kafka.Message {
+	msg := newKafkaMessage(message)
+	if _, ok := s.priorities[message.Topic]; ok {
+		msg.Topic, _ = priorityTopicAndGroup(message.Topic, message.PriorityLevel())
+	}
+	return msg
+}
+
+// PriorityKafkaTopic возвращает имя топика Kafka уровня приоритета типа сообщений.
+func PriorityKafkaTopic(messageType, priority string) string {
+	topic, _ := priorityTopicAndGroup(messageType, priority)
+	return topic
+}
+
+// priorityTopicAndGroup возвращает топик и группу Kafka уровня приоритета. Уровень normal использует
+// основной топик типа, поэтому смещения и публикаторы типов без приоритетов не меняются.
+func priorityTopicAndGroup(messageType, priority string) (string, string) {
+	if priority == utils.PriorityNormal || priority == "" {
+		return getTopicAndGroup(messageType)
+	}
+	return getTopicAndGroup(messageType + "-" + priority)
+}
+
+// priorityFromTopic определяет уровень приоритета по имени топика Kafka: <тип>-<уровень>-topic.
+func priorityFromTopic(topic string) string {
+	parts := strings.Split(topic, "-")
+	if len(parts) == 3 && (parts[1] == utils.PriorityHigh || parts[1] == utils.PriorityLow) {
+		return parts[1]
+	}
+	return ""
+}
+
+// priorityLevel - уровень приоритета консьюмера: сообщения, прочитанные из его топика, и вес уровня.
+type priorityLevel struct {
+	priority string
+	weight   int
+	reader   levelReader
+	messages chan kafka.Message
+}
+
+// weightedDrain выбирает следующее сообщение из уровней приоритета. За цикл из уровня берется не больше
+// weight сообщений, начиная с более высокого: срочные сообщения обрабатываются первыми, а остальные
+// уровни получают свою долю и не простаивают при постоянном потоке срочных сообщений.
+type weightedDrain struct {
+	levels  []*priorityLevel
+	credits []int
+}
+
+func newWeightedDrain(levels []*priorityLevel) *weightedDrain {
+	d := &weightedDrain{levels: levels, credits: make([]int, len(levels))}
+	d.reset()
+	return d
+}
+
+func (d *weightedDrain) reset() {
+	for i, level := range d.levels {
+		d.credits[i] = level.weight
+	}
+}
+
+// next возвращает уровень и сообщение. false - если ctx отменен или чтение уровня завершено.
+func (d *weightedDrain) next(ctx context.Context) (*priorityLevel, kafka.Message, bool) {
+	for {
+		// Уровни с оставшейся долей цикла по убыванию приоритета
+		spent := false
+		for i, level := range d.levels {
+			if d.credits[i] < level.weight {
+				spent = true
+			}
+			if d.credits[i] == 0 {
+				continue
+			}
+			select {
+			case msg, ok := <-level.messages:
+				if !ok {
+					return nil, msg, false
+				}
+				d.credits[i]--
+				return level, msg, true
+			default:
+			}
+		}
+		// Уровни с оставшейся долей пусты: начинаем новый цикл
+		if spent {
+			d.reset()
+			continue
+		}
+		return d.wait(ctx)
+	}
+}
+
+// wait ждет первое сообщение любого уровня, когда все уровни пусты.
+func (d *weightedDrain) wait(ctx context.Context) (*priorityLevel, kafka.Message, bool) {
+	// Первый вариант - отмена ctx, остальные - уровни в порядке d.levels
+	cases := make([]reflect.SelectCase, 0, len(d.levels)+1)
+	cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())})
+	for _, level := range d.levels {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(level.messages)})
+	}
+
+	chosen, value, ok := reflect.Select(cases)
+	if chosen == 0 || !ok {
+		return nil, kafka.Message{}, false
+	}
+	index := chosen - 1
+	d.credits[index]--
+	return d.levels[index], value.Interface().(kafka.Message), true
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"ProjectMessageService/internal/utils"
+	"context"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriorityTopics(t *testing.T) {
+	s := &MessageService{priorities: map[string]map[string]int{"message": {"high": 6, "normal": 3, "low": 1}}}
+
+	msg := s.kafkaMessage(utils.Message{Topic: "message", Message: "urgent", Priority: utils.PriorityHigh})
+	require.Equal(t, "message-high-topic", msg.Topic)
+	require.Equal(t, utils.PriorityHigh, messageFromKafka(msg).PriorityLevel())
+	require.Equal(t, "message", messageFromKafka(msg).Topic)
+
+	msg = s.kafkaMessage(utils.Message{Topic: "message", Message: "bulk"})
+	require.Equal(t, "message-topic", msg.Topic)
+	require.Equal(t, utils.PriorityNormal, messageFromKafka(msg).PriorityLevel())
+
+	// Тип без уровней приоритета публикуется в основной топик
+	msg = s.kafkaMessage(utils.Message{Topic: "ping", Message: "urgent", Priority: utils.PriorityHigh})
+	require.Equal(t, "ping-topic", msg.Topic)
+	require.Len(t, s.messagePriorities("ping"), 1)
+	require.Len(t, s.messagePriorities("message"), 3)
+}
+
+func TestWeightedDrain(t *testing.T) {
+	levels := []*priorityLevel{
+		{priority: utils.PriorityHigh, weight: 3, messages: make(chan kafka.Message, 10)},
+		{priority: utils.PriorityNormal, weight: 2, messages: make(chan kafka.Message, 10)},
+		{priority: utils.PriorityLow, weight: 1, messages: make(chan kafka.Message, 10)},
+	}
+	for i := 0; i < 10; i++ {
+		for _, level := range levels {
+			level.messages <- kafka.Message{Topic: level.priority}
+		}
+	}
+
+	drain := newWeightedDrain(levels)
+	var order []string
+	for i := 0; i < 12; i++ {
+		_, msg, ok := drain.next(context.Background())
+		require.True(t, ok)
+		order = append(order, msg.Topic)
+	}
+	require.Equal(t, []string{
+		"high", "high", "high", "normal", "normal", "low",
+		"high", "high", "high", "normal", "normal", "low",
+	}, order)
+
+	// Пустые уровни не задерживают остальные
+	for len(levels[0].messages) > 0 {
+		<-levels[0].messages
+	}
+	for len(levels[1].messages) > 0 {
+		<-levels[1].messages
+	}
+	_, msg, ok := drain.next(context.Background())
+	require.True(t, ok)
+	require.Equal(t, "low", msg.Topic)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for len(levels[2].messages) > 0 {
+		<-levels[2].messages
+	}
+	_, _, ok = drain.next(ctx)
+	require.False(t, ok)
+}
+
+func TestWeightedDrainWait(t *testing.T) {
+	// Число уровней не ограничено тремя
+	levels := make([]*priorityLevel, 4)
+	for i := range levels {
+		levels[i] = &priorityLevel{weight: 1, messages: make(chan kafka.Message)}
+	}
+	drain := newWeightedDrain(levels)
+
+	go func() { levels[3].messages <- kafka.Message{Offset: 3} }()
+	level, msg, ok := drain.next(context.Background())
+	require.True(t, ok)
+	require.Equal(t, levels[3], level)
+	require.EqualValues(t, 3, msg.Offset)
+
+	close(levels[1].messages)
+	_, _, ok = drain.next(context.Background())
+	require.False(t, ok)
+}
+
+func TestMissingPriorityTopics(t *testing.T) {
+	priorities := map[string]map[string]int{"message": {"high": 2, "normal": 1, "low": 1}, "ping": {"high": 1, "normal": 1, "low": 1}}
+	existing := map[string]bool{"message-topic": true, "message-high-topic": true, "message-low-topic": true, "ping-high-topic": true}
+
+	require.Equal(t, []string{"ping-low-topic"}, missingPriorityTopics(priorities, existing))
+	require.Empty(t, missingPriorityTopics(nil, existing))
+}
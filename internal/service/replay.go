@@ -0,0 +1,106 @@
+package service
+
+import (
+	"ProjectMessageService/internal/metrics"
+	"ProjectMessageService/internal/repository"
+	"context"
+	"fmt"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaTopic возвращает имя топика Kafka для типа сообщений.
+func KafkaTopic(messageType string) string {
+	topic, _ := getTopicAndGroup(messageType)
+	return topic
+}
+
+// Republish повторно публикует сохраненные сообщения в Kafka. Консьюмер не создаст дубликатов в базе,
+// так как SaveMessage пропускает сообщения с уже сохраненным содержимым, а уже обработанные сообщения
+// пропустит, если для них не запрошена повторная обработка.
+func (s *MessageService) Republish(ctx context.Context, messages []repository.StoredMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	kafkaMessages := make([]kafka.Message, 0, len(messages))
+	for _, message := range messages {
+		kafkaMessages = append(kafkaMessages, s.kafkaMessage(message.Message))
+	}
+	if err := s.publish(ctx, kafkaMessages...); err != nil {
+		s.app.Log.WithCtx(ctx).Errorf("Не удалось повторно опубликовать сообщения: %v", err)
+		return err
+	}
+
+	for _, message := range messages {
+		metrics.MessagePublished(message.Topic)
+	}
+	return nil
+}
+
+// replayBatchSize - сколько сообщений читается из базы и публикуется за один раз.
+const replayBatchSize = 500
+
+// ReprocessParams - какие сообщения базы отправить на повторную обработку.
+type ReprocessParams struct {
+	Filter repository.ListMessagesParams // Limit фильтра игнорируется
+	Limit  int                           // Не больше Limit сообщений, 0 - без ограничения
+	DryRun bool                          // Только посчитать подходящие сообщения
+}
+
+// ReprocessMessages отмечает подходящие сообщения для повторной обработки и снова публикует их в Kafka.
+// Возвращает число подходящих (при DryRun) или повторно опубликованных сообщений.
+func (s *MessageService) ReprocessMessages(ctx context.Context, arg ReprocessParams) (int, error) {
+	return s.reprocessMessages(ctx, arg, nil)
+}
+
+func (s *MessageService) reprocessMessages(ctx context.Context, arg ReprocessParams, progress func(int)) (int, error) {
+	return reprocessBatches(ctx, arg, s.repo.ListMessages, func(ctx context.Context, messages []repository.StoredMessage) error {
+		ids := make([]int, len(messages))
+		for i, message := range messages {
+			ids[i] = message.ID
+		}
+		if _, err := s.repo.RequestReprocess(ctx, arg.Filter.Topic, ids); err != nil {
+			return err
+		}
+		return s.Republish(ctx, messages)
+	}, progress)
+}
+
+// reprocessBatches читает сообщения по фильтру пачками по replayBatchSize и передает каждую пачку в apply
+// (при DryRun только считает их). progress, если задан, получает число сообщений после каждой пачки.
+func reprocessBatches(ctx context.Context, arg ReprocessParams,
+	list func(context.Context, repository.ListMessagesParams) ([]repository.StoredMessage, error),
+	apply func(context.Context, []repository.StoredMessage) error, progress func(int)) (int, error) {
+	filter := arg.Filter
+	total := 0
+	for arg.Limit == 0 || total < arg.Limit {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		filter.Limit = replayBatchSize
+		if arg.Limit > 0 && arg.Limit-total < filter.Limit {
+			filter.Limit = arg.Limit - total
+		}
+
+		messages, err := list(ctx, filter)
+		if err != nil {
+			return total, err
+		}
+		if len(messages) == 0 {
+			break
+		}
+		if !arg.DryRun {
+			if err = apply(ctx, messages); err != nil {
+				return total, fmt.Errorf("replayed %d messages before error: %w", total, err)
+			}
+		}
+
+		total += len(messages)
+		filter.AfterID = messages[len(messages)-1].ID
+		if progress != nil {
+			progress(total)
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"ProjectMessageService/internal/repository"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// storedMessages имитирует ListMessages по таблице с сообщениями id 1..count.
+func storedMessages(count int) func(context.Context, repository.ListMessagesParams) ([]repository.StoredMessage, error) {
+	return func(_ context.Context, arg repository.ListMessagesParams) ([]repository.StoredMessage, error) {
+		var messages []repository.StoredMessage
+		for id := arg.AfterID + 1; id <= count && len(messages) < arg.Limit; id++ {
+			messages = append(messages, repository.StoredMessage{ID: id})
+		}
+		return messages, nil
+	}
+}
+
+func TestReprocessBatches(t *testing.T) {
+	var batches [][]int
+	apply := func(_ context.Context, messages []repository.StoredMessage) error {
+		ids := make([]int, len(messages))
+		for i, message := range messages {
+			ids[i] = message.ID
+		}
+		batches = append(batches, ids)
+		return nil
+	}
+
+	var progress []int
+	total, err := reprocessBatches(context.Background(), ReprocessParams{Filter: repository.ListMessagesParams{AfterID: 100}, Limit: 700},
+		storedMessages(1500), apply, func(total int) { progress = append(progress, total) })
+	require.NoError(t, err)
+	require.Equal(t, 700, total)
+	require.Len(t, batches, 2)
+	require.Len(t, batches[0], replayBatchSize)
+	require.Equal(t, 101, batches[0][0])
+	require.Equal(t, 800, batches[1][len(batches[1])-1])
+	require.Equal(t, []int{500, 700}, progress)
+
+	// Без лимита читаются все сообщения, dry run их только считает
+	batches = nil
+	total, err = reprocessBatches(context.Background(), ReprocessParams{DryRun: true}, storedMessages(1200), apply, nil)
+	require.NoError(t, err)
+	require.Equal(t, 1200, total)
+	require.Empty(t, batches)
+}
+
+func TestReprocessBatchesStopsOnError(t *testing.T) {
+	calls := 0
+	total, err := reprocessBatches(context.Background(), ReprocessParams{}, storedMessages(1200),
+		func(context.Context, []repository.StoredMessage) error {
+			if calls++; calls == 2 {
+				return errors.New("kafka unavailable")
+			}
+			return nil
+		}, nil)
+	require.Error(t, err)
+	require.Equal(t, replayBatchSize, total)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	total, err = reprocessBatches(ctx, ReprocessParams{}, storedMessages(10), nil, nil)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Zero(t, total)
+}
+
+func TestReprocessJobs(t *testing.T) {
+	jobs := newReprocessJobs()
+
+	done := jobs.start("ping", func(_ context.Context, progress func(int)) (int, error) {
+		progress(10)
+		return 20, nil
+	})
+	require.Equal(t, JobRunning, done.Status)
+
+	failed := jobs.start("ping", func(context.Context, func(int)) (int, error) {
+		return 5, errors.New("kafka unavailable")
+	})
+
+	started := make(chan struct{})
+	canceled := jobs.start("ping", func(ctx context.Context, _ func(int)) (int, error) {
+		close(started)
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+	<-started
+	require.Eventually(t, func() bool {
+		job, _ := jobs.get(failed.ID)
+		return job.Status != JobRunning
+	}, time.Second, time.Millisecond)
+	jobs.stop()
+
+	job, ok := jobs.get(done.ID)
+	require.True(t, ok)
+	require.Equal(t, JobDone, job.Status)
+	require.Equal(t, 20, job.Republished)
+	require.NotNil(t, job.FinishedAt)
+
+	job, _ = jobs.get(failed.ID)
+	require.Equal(t, JobFailed, job.Status)
+	require.Equal(t, 5, job.Republished)
+	require.Equal(t, "kafka unavailable", job.Error)
+
+	job, _ = jobs.get(canceled.ID)
+	require.Equal(t, JobCanceled, job.Status)
+
+	_, ok = jobs.get(canceled.ID + 1)
+	require.False(t, ok)
+}
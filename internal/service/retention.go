@@ -0,0 +1,89 @@
+package service
+
+import (
+	"ProjectMessageService/config"
+	"ProjectMessageService/internal/metrics"
+	"ProjectMessageService/internal/repository"
+	"context"
+	"sync"
+	"time"
+)
+
+// RetentionOptions - параметры фоновой очистки топиков по политикам хранения.
+type RetentionOptions struct {
+	Interval   time.Duration // Как часто применяются политики
+	BatchSize  int           // Сколько сообщений удаляется одной транзакцией
+	ArchiveDir string        // Каталог архивов NDJSON.gz удаленных сообщений, пусто - без архива
+}
+
+// retentionPolicies - политики хранения топиков из RETENTION.
+type retentionPolicies struct {
+	mu       sync.RWMutex
+	policies map[string]config.RetentionPolicy
+}
+
+// SetRetentionPolicies заменяет политики хранения топиков, в том числе после перезагрузки конфигурации.
+func (s *MessageService) SetRetentionPolicies(policies map[string]config.RetentionPolicy) {
+	s.retention.mu.Lock()
+	defer s.retention.mu.Unlock()
+	s.retention.policies = policies
+}
+
+// RunRetention применяет политики хранения каждые opts.Interval до отмены ctx.
+func (s *MessageService) RunRetention(ctx context.Context, opts RetentionOptions) {
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		s.retention.mu.RLock()
+		policies := s.retention.policies
+		s.retention.mu.RUnlock()
+
+		for topic, policy := range policies {
+			if ctx.Err() != nil {
+				return
+			}
+			s.applyRetention(ctx, topic, policy, opts)
+		}
+	}
+}
+
+// applyRetention удаляет сообщения топика, нарушающие политику, пачками по opts.BatchSize,
+// чтобы не блокировать таблицу надолго.
+func (s *MessageService) applyRetention(ctx context.Context, topic string, policy config.RetentionPolicy, opts RetentionOptions) {
+	arg := repository.RetentionParams{
+		Topic:           topic,
+		KeepRows:        policy.MaxRows,
+		KeepUnprocessed: policy.KeepUnprocessed,
+		Limit:           opts.BatchSize,
+	}
+	if policy.MaxAge > 0 {
+		arg.Before = time.Now().Add(-policy.MaxAge)
+	}
+	archive := archiveFunc(opts.ArchiveDir, topic)
+
+	total := 0
+	for ctx.Err() == nil {
+		deleted, err := s.repo.PurgeMessages(ctx, arg, archive)
+		if err != nil {
+			if ctx.Err() == nil {
+				s.app.Log.Errorf("Не удалось применить политику хранения топика %s: %v", topic, err)
+			}
+			break
+		}
+		total += deleted
+		metrics.MessagesPurged(topic, metrics.PurgeRetention, int64(deleted))
+		if deleted < opts.BatchSize {
+			break
+		}
+	}
+	if total > 0 {
+		s.app.Log.Infof("Политика хранения топика %s: удалено %d сообщений", topic, total)
+	}
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"ProjectMessageService/internal/processor"
+	"ProjectMessageService/internal/routing"
+	"ProjectMessageService/internal/utils"
+	"ProjectMessageService/util"
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// routingCacheTTL - как долго консьюмер использует загруженные правила топика. Изменения правил через API
+	// этого процесса применяются сразу, других реплик - не позже чем через routingCacheTTL.
+	routingCacheTTL = 10 * time.Second
+	// routingMaxHops ограничивает цепочки пересылок между топиками, чтобы правила не зациклили сообщение.
+	routingMaxHops = 3
+
+	routeHopsHeader = "x-route-hops"
+)
+
+// RoutingStore - хранилище правил маршрутизации.
+type RoutingStore interface {
+	ListRoutingRules(ctx context.Context, topic string) ([]routing.Rule, error)
+}
+
+// Router проверяет правила маршрутизации топиков, кэшируя скомпилированные правила на routingCacheTTL.
+type Router struct {
+	store RoutingStore
+	mu    sync.Mutex
+	cache map[string]cachedRules
+}
+
+type cachedRules struct {
+	rules    *routing.RuleSet
+	loadedAt time.Time
+}
+
+func NewRouter(store RoutingStore) *Router {
+	return &Router{store: store, cache: make(map[string]cachedRules)}
+}
+
+// Invalidate сбрасывает кэш правил после их изменения.
+func (r *Router) Invalidate() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cache = make(map[string]cachedRules)
+}
+
+// Evaluate проверяет правила топика сообщения.
+func (r *Router) Evaluate(ctx context.Context, message utils.Message) (routing.Result, error) {
+	rules, err := r.rules(ctx, message.Topic)
+	if err != nil {
+		return routing.Result{Deliver: true}, err
+	}
+	return rules.Evaluate(message)
+}
+
+func (r *Router) rules(ctx context.Context, topic string) (*routing.RuleSet, error) {
+	r.mu.Lock()
+	cached, ok := r.cache[topic]
+	r.mu.Unlock()
+	if ok && time.Since(cached.loadedAt) < routingCacheTTL {
+		return cached.rules, nil
+	}
+
+	list, err := r.store.ListRoutingRules(ctx, topic)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := routing.Compile(list)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	r.cache[topic] = cachedRules{rules: rules, loadedAt: time.Now()}
+	r.mu.Unlock()
+	return rules, nil
+}
+
+// InvalidateRoutes применяет изменение правил в этом процессе без ожидания routingCacheTTL.
+func (s *MessageService) InvalidateRoutes() {
+	s.router.Invalidate()
+}
+
+// TestRoutes проверяет сообщение по активным правилам его топика без публикации.
+func (s *MessageService) TestRoutes(ctx context.Context, message utils.Message) (routing.Result, error) {
+	rules, err := s.router.store.ListRoutingRules(ctx, message.Topic)
+	if err != nil {
+		return routing.Result{}, err
+	}
+	return routing.Evaluate(rules, message)
+}
+
+// routeStep - шаг конвейера route: правила маршрутизации могут скопировать сообщение в другие топики,
+// переслать или отбросить его (processor.ErrDrop).
+func (s *MessageService) routeStep(ctx context.Context, env *processor.Envelope) error {
+	deliver, err := s.route(ctx, env.ID, env.Message)
+	if err != nil {
+		return err
+	}
+	if !deliver {
+		return processor.ErrDrop
+	}
+	return nil
+}
+
+// route публикует сообщение в топики сработавших правил и возвращает false,
+// если сообщение переслано или отброшено и не должно доставляться подписчикам исходного топика.
+func (s *MessageService) route(ctx context.Context, id int, message utils.Message) (bool, error) {
+	result, err := s.router.Evaluate(ctx, message)
+	if err != nil {
+		s.app.Log.WithCtx(ctx).Errorf("Не удалось проверить правила маршрутизации топика %s: %v", message.Topic, err)
+		return true, err
+	}
+	if len(result.Matched) > 0 {
+		s.app.Log.WithCtx(ctx).Infof("Сообщение %d топика %s: сработали правила %v", id, message.Topic, result.Matched)
+	}
+	if len(result.Targets) == 0 {
+		return result.Deliver, nil
+	}
+
+	hops, _ := strconv.Atoi(message.Headers[routeHopsHeader])
+	if hops >= routingMaxHops {
+		s.app.Log.WithCtx(ctx).Warnf("Сообщение %d топика %s переслано %d раз, маршрутизация пропущена", id, message.Topic, hops)
+		return result.Deliver, nil
+	}
+
+	routed := make([]utils.Message, 0, len(result.Targets))
+	for _, target := range result.Targets {
+		if !util.IsSupportedCurrency(target) {
+			s.app.Log.WithCtx(ctx).Errorf("Топик %s правила маршрутизации не входит в MESSAGE_TYPES, сообщение %d не переслано", target, id)
+			continue
+		}
+		copied := message
+		copied.Topic = target
+		copied.Headers = make(map[string]string, len(message.Headers)+2)
+		for key, value := range message.Headers {
+			copied.Headers[key] = value
+		}
+		copied.Headers[utils.RoutedFromHeader] = fmt.Sprintf("%s/%d", message.Topic, id)
+		copied.Headers[routeHopsHeader] = strconv.Itoa(hops + 1)
+		routed = append(routed, copied)
+	}
+	if len(routed) == 0 {
+		return result.Deliver, nil
+	}
+
+	results, err := s.SaveMessages(ctx, routed)
+	if err != nil {
+		return true, err
+	}
+	for _, saved := range results {
+		if saved.Status == utils.BatchStatusFailed {
+			return true, fmt.Errorf("cannot route message to %s: %s", saved.Topic, saved.Error)
+		}
+	}
+	return result.Deliver, nil
+}
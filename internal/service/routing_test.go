@@ -0,0 +1,43 @@
+package service
+
+import (
+	"ProjectMessageService/internal/routing"
+	"ProjectMessageService/internal/utils"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeRoutingStore struct {
+	rules []routing.Rule
+	loads int
+}
+
+func (s *fakeRoutingStore) ListRoutingRules(context.Context, string) ([]routing.Rule, error) {
+	s.loads++
+	return s.rules, nil
+}
+
+func TestRouterInvalidate(t *testing.T) {
+	store := &fakeRoutingStore{rules: []routing.Rule{{ID: 1, Topic: "message", Action: routing.ActionDrop, IsActive: true,
+		Conditions: []routing.Condition{{Type: routing.ConditionContent, Matches: `^spam`}}}}}
+	router := NewRouter(store)
+	ctx := context.Background()
+	message := utils.Message{Topic: "message", Message: "spam"}
+
+	result, err := router.Evaluate(ctx, message)
+	require.NoError(t, err)
+	require.False(t, result.Deliver)
+	_, err = router.Evaluate(ctx, message)
+	require.NoError(t, err)
+	require.Equal(t, 1, store.loads)
+
+	// После изменения правил набор загружается и компилируется заново
+	store.rules[0].Conditions[0].Matches = `^ham`
+	router.Invalidate()
+	result, err = router.Evaluate(ctx, message)
+	require.NoError(t, err)
+	require.True(t, result.Deliver)
+	require.Equal(t, 2, store.loads)
+}
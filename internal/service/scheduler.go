@@ -0,0 +1,79 @@
+package service
+
+import (
+	"ProjectMessageService/internal/repository"
+	"ProjectMessageService/internal/utils"
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	schedulerInterval  = time.Second // Как часто проверяются сообщения, время доставки которых наступило
+	schedulerBatchSize = 100
+	schedulerClaim     = time.Minute // Через сколько сообщения, публикацию которых не завершила остановленная реплика, публикуются повторно
+)
+
+// ScheduleMessage сохраняет сообщение для публикации в deliverAt.
+func (s *MessageService) ScheduleMessage(ctx context.Context, message utils.Message, deliverAt time.Time, username string) (repository.ScheduledMessage, error) {
+	scheduled, err := s.repo.CreateScheduledMessage(ctx, repository.CreateScheduledMessageParams{
+		Message:   message,
+		DeliverAt: deliverAt,
+		CreatedBy: username,
+	})
+	if err != nil {
+		s.app.Log.WithCtx(ctx).Errorf("Не удалось сохранить отложенное сообщение: %v", err)
+		return scheduled, err
+	}
+	s.app.Log.WithCtx(ctx).Infof("Сообщение %d топика %s будет опубликовано в %s", scheduled.ID, scheduled.Topic, deliverAt.Format(time.RFC3339))
+	return scheduled, nil
+}
+
+// RunScheduler публикует отложенные сообщения, время доставки которых наступило, до отмены ctx.
+// Сообщения хранятся в базе, поэтому после перезапуска публикуются пропущенные за время простоя.
+func (s *MessageService) RunScheduler(ctx context.Context) {
+	ticker := time.NewTicker(schedulerInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// Публикуем пачками, пока есть просроченные сообщения
+		for ctx.Err() == nil {
+			delivered, err := s.repo.DeliverDueScheduledMessages(ctx, schedulerBatchSize, schedulerClaim, s.deliverScheduled)
+			if err != nil {
+				if ctx.Err() == nil {
+					s.app.Log.Errorf("Не удалось опубликовать отложенные сообщения: %v", err)
+				}
+				break
+			}
+			if delivered < schedulerBatchSize {
+				break
+			}
+		}
+	}
+}
+
+// deliverScheduled публикует сообщения так же, как POST /messages/batch. Если хотя бы одно не опубликовано
+// или публикация прервана остановкой, вся пачка останется в очереди и будет опубликована повторно;
+// дубликаты консьюмер не обработает дважды.
+func (s *MessageService) deliverScheduled(ctx context.Context, scheduled []repository.ScheduledMessage) error {
+	messages := make([]utils.Message, len(scheduled))
+	for i, item := range scheduled {
+		messages[i] = item.Message.Immediate()
+	}
+	results, err := s.SaveMessages(ctx, messages)
+	if err != nil {
+		return err
+	}
+	for i, result := range results {
+		if result.Status == utils.BatchStatusFailed {
+			return fmt.Errorf("scheduled message %d: %s", scheduled[i].ID, result.Error)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"ProjectMessageService/config"
+	"ProjectMessageService/internal/repository"
+	"ProjectMessageService/internal/utils"
+	"ProjectMessageService/util"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// schemaCacheTTL - время, в течение которого скомпилированная схема топика не перечитывается из базы.
+// Ограничивает задержку, с которой реплика узнает о схеме, зарегистрированной другой репликой.
+const schemaCacheTTL = 30 * time.Second
+
+// ErrInvalidSchema возвращается, если переданный документ не является корректной JSON Schema.
+var ErrInvalidSchema = errors.New("invalid schema")
+
+// ErrCompatibilityChange возвращается при попытке зарегистрировать версию схемы с другим режимом совместимости.
+var ErrCompatibilityChange = errors.New("schema compatibility mode cannot be changed")
+
+// ErrSchemaVersionConflict возвращается, если ту же версию схемы одновременно зарегистрировал другой запрос.
+var ErrSchemaVersionConflict = errors.New("schema version already registered")
+
+// FieldError - ошибка проверки одного поля сообщения.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// SchemaValidationError возвращается, если payload сообщения не соответствует схеме топика.
+type SchemaValidationError struct {
+	Topic   string       `json:"topic"`
+	Version int          `json:"schema_version"`
+	Errors  []FieldError `json:"errors"`
+}
+
+func (e *SchemaValidationError) Error() string {
+	fields := make([]string, 0, len(e.Errors))
+	for _, fieldErr := range e.Errors {
+		fields = append(fields, fieldErr.Field+": "+fieldErr.Message)
+	}
+	return fmt.Sprintf("message does not match schema v%d of topic %s: %s", e.Version, e.Topic, strings.Join(fields, "; "))
+}
+
+// SchemaCompatibilityError возвращается, если новая версия схемы несовместима с предыдущей.
+type SchemaCompatibilityError struct {
+	Compatibility string   `json:"compatibility"`
+	Problems      []string `json:"problems"`
+}
+
+func (e *SchemaCompatibilityError) Error() string {
+	return fmt.Sprintf("schema is not %s compatible: %s", e.Compatibility, strings.Join(e.Problems, "; "))
+}
+
+type cachedSchema struct {
+	version  int
+	schema   *jsonschema.Schema // nil, если у топика нет схемы
+	loadedAt time.Time
+}
+
+// SchemaRegistry хранит версии JSON Schema топиков и проверяет по ним сообщения.
+type SchemaRegistry struct {
+	repo  *repository.Repository
+	app   *config.Application
+	mu    sync.RWMutex
+	cache map[string]cachedSchema
+}
+
+func NewSchemaRegistry(repo *repository.Repository, app *config.Application) *SchemaRegistry {
+	return &SchemaRegistry{repo: repo, app: app, cache: make(map[string]cachedSchema)}
+}
+
+// Register проверяет схему, ее совместимость с последней версией топика и сохраняет ее как новую версию.
+// Режим совместимости задается первой версией схемы топика (по умолчанию backward) и применяется ко всем
+// следующим версиям; compatibility последующих версий может быть пустым или совпадать с ним.
+func (r *SchemaRegistry) Register(ctx context.Context, topic string, schema json.RawMessage, compatibility string) (repository.TopicSchema, error) {
+	compiled, err := compileSchema(topic, schema)
+	if err != nil {
+		return repository.TopicSchema{}, err
+	}
+
+	version := 1
+	latest, err := r.repo.GetLatestTopicSchema(ctx, topic)
+	switch {
+	case err == nil:
+		version = latest.Version + 1
+		if compatibility, err = topicCompatibility(compatibility, &latest); err != nil {
+			return repository.TopicSchema{}, err
+		}
+		var previous, next map[string]interface{}
+		if err = json.Unmarshal(latest.Schema, &previous); err != nil {
+			return repository.TopicSchema{}, err
+		}
+		if err = json.Unmarshal(schema, &next); err != nil {
+			return repository.TopicSchema{}, err
+		}
+		if problems := checkCompatibility(compatibility, previous, next); len(problems) > 0 {
+			return repository.TopicSchema{}, &SchemaCompatibilityError{Compatibility: compatibility, Problems: problems}
+		}
+	case errors.Is(err, pgx.ErrNoRows):
+		compatibility, _ = topicCompatibility(compatibility, nil)
+	default:
+		return repository.TopicSchema{}, err
+	}
+
+	saved, err := r.repo.CreateTopicSchema(ctx, repository.CreateTopicSchemaParams{
+		Topic:         topic,
+		Version:       version,
+		Schema:        schema,
+		Compatibility: compatibility,
+	})
+	if util.ErrorCode(err) == util.UniqueViolation {
+		return repository.TopicSchema{}, fmt.Errorf("%w: version %d of topic %s", ErrSchemaVersionConflict, version, topic)
+	}
+	if err != nil {
+		return repository.TopicSchema{}, err
+	}
+
+	r.mu.Lock()
+	r.cache[topic] = cachedSchema{version: saved.Version, schema: compiled, loadedAt: time.Now()}
+	r.mu.Unlock()
+
+	r.app.Log.WithCtx(ctx).Infof("Зарегистрирована схема топика %s версии %d", topic, saved.Version)
+	return saved, nil
+}
+
+// topicCompatibility возвращает режим совместимости новой версии схемы: режим последней версии latest
+// или, для первой версии, запрошенный режим (по умолчанию backward).
+func topicCompatibility(requested string, latest *repository.TopicSchema) (string, error) {
+	if latest == nil {
+		if requested == "" {
+			return CompatibilityBackward, nil
+		}
+		return requested, nil
+	}
+	if requested != "" && requested != latest.Compatibility {
+		return "", fmt.Errorf("%w: topic %s uses %s", ErrCompatibilityChange, latest.Topic, latest.Compatibility)
+	}
+	return latest.Compatibility, nil
+}
+
+// Latest возвращает последнюю версию схемы топика.
+func (r *SchemaRegistry) Latest(ctx context.Context, topic string) (repository.TopicSchema, error) {
+	return r.repo.GetLatestTopicSchema(ctx, topic)
+}
+
+// Validate проверяет payload сообщения по последней схеме топика. Если схема не зарегистрирована,
+// сообщение считается корректным.
+func (r *SchemaRegistry) Validate(ctx context.Context, message utils.Message) error {
+	cached, err := r.get(ctx, message.Topic)
+	if err != nil {
+		return err
+	}
+	if cached.schema == nil {
+		return nil
+	}
+
+	validationErr := &SchemaValidationError{Topic: message.Topic, Version: cached.version}
+	if len(message.Payload) == 0 {
+		validationErr.Errors = []FieldError{{Field: "payload", Message: "topic requires a JSON payload"}}
+		return validationErr
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(message.Payload))
+	decoder.UseNumber()
+	var doc interface{}
+	if err = decoder.Decode(&doc); err != nil {
+		validationErr.Errors = []FieldError{{Field: "payload", Message: err.Error()}}
+		return validationErr
+	}
+
+	err = cached.schema.Validate(doc)
+	var schemaErr *jsonschema.ValidationError
+	if errors.As(err, &schemaErr) {
+		validationErr.Errors = fieldErrors(schemaErr)
+		return validationErr
+	}
+	return err
+}
+
+func (r *SchemaRegistry) get(ctx context.Context, topic string) (cachedSchema, error) {
+	r.mu.RLock()
+	cached, ok := r.cache[topic]
+	r.mu.RUnlock()
+	if ok && time.Since(cached.loadedAt) < schemaCacheTTL {
+		return cached, nil
+	}
+
+	cached = cachedSchema{loadedAt: time.Now()}
+	latest, err := r.repo.GetLatestTopicSchema(ctx, topic)
+	switch {
+	case err == nil:
+		if cached.schema, err = compileSchema(topic, latest.Schema); err != nil {
+			return cachedSchema{}, err
+		}
+		cached.version = latest.Version
+	case errors.Is(err, pgx.ErrNoRows):
+	default:
+		r.app.Log.WithCtx(ctx).Errorf("Не удалось загрузить схему топика %s: %v", topic, err)
+		return cachedSchema{}, err
+	}
+
+	r.mu.Lock()
+	r.cache[topic] = cached
+	r.mu.Unlock()
+	return cached, nil
+}
+
+func compileSchema(topic string, schema json.RawMessage) (*jsonschema.Schema, error) {
+	compiled, err := jsonschema.CompileString(topic+".schema.json", string(schema))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	return compiled, nil
+}
+
+// fieldErrors собирает конечные причины ошибки проверки с путями к полям payload.
+func fieldErrors(err *jsonschema.ValidationError) []FieldError {
+	if len(err.Causes) == 0 {
+		field := "payload" + err.InstanceLocation
+		return []FieldError{{Field: field, Message: err.Message}}
+	}
+
+	var result []FieldError
+	for _, cause := range err.Causes {
+		result = append(result, fieldErrors(cause)...)
+	}
+	return result
+}
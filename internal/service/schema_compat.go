@@ -0,0 +1,139 @@
+package service
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Режимы совместимости при регистрации новой версии схемы.
+const (
+	CompatibilityBackward = "backward" // новая схема принимает сообщения, записанные по предыдущей
+	CompatibilityForward  = "forward"  // предыдущая схема принимает сообщения, записанные по новой
+	CompatibilityFull     = "full"     // выполняются оба условия
+	CompatibilityNone     = "none"     // проверка не выполняется
+)
+
+// checkCompatibility проверяет новую версию схемы относительно предыдущей в заданном режиме
+// и возвращает список найденных несовместимостей.
+func checkCompatibility(mode string, previous, next map[string]interface{}) []string {
+	var problems []string
+	switch mode {
+	case CompatibilityBackward:
+		problems = checkSchemaCompatibility(next, previous, "")
+	case CompatibilityForward:
+		problems = checkSchemaCompatibility(previous, next, "")
+	case CompatibilityFull:
+		problems = append(checkSchemaCompatibility(next, previous, ""), checkSchemaCompatibility(previous, next, "")...)
+	}
+	return problems
+}
+
+// checkSchemaCompatibility проверяет, что документы, корректные по схеме writer, проходят проверку
+// схемой reader. Проверка упрощенная и учитывает ключевые слова type, required, properties,
+// additionalProperties, enum и items.
+func checkSchemaCompatibility(reader, writer map[string]interface{}, path string) []string {
+	var problems []string
+	location := path
+	if location == "" {
+		location = "/"
+	}
+
+	readerTypes, writerTypes := schemaTypes(reader), schemaTypes(writer)
+	if len(readerTypes) > 0 {
+		if len(writerTypes) == 0 {
+			problems = append(problems, fmt.Sprintf("%s: type is restricted to %v", location, sortedKeys(readerTypes)))
+		}
+		for _, t := range sortedKeys(writerTypes) {
+			if !readerTypes[t] && !(t == "integer" && readerTypes["number"]) {
+				problems = append(problems, fmt.Sprintf("%s: type %q is no longer accepted", location, t))
+			}
+		}
+	}
+
+	writerRequired := stringSet(writer["required"])
+	for _, field := range sortedKeys(stringSet(reader["required"])) {
+		if !writerRequired[field] {
+			problems = append(problems, fmt.Sprintf("%s: field %q became required", location, field))
+		}
+	}
+
+	if readerEnum, ok := reader["enum"].([]interface{}); ok {
+		writerEnum, ok := writer["enum"].([]interface{})
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: values are restricted by enum", location))
+		}
+		for _, value := range writerEnum {
+			if !containsValue(readerEnum, value) {
+				problems = append(problems, fmt.Sprintf("%s: enum value %v is no longer accepted", location, value))
+			}
+		}
+	}
+
+	readerProps, _ := reader["properties"].(map[string]interface{})
+	writerProps, _ := writer["properties"].(map[string]interface{})
+	closed := reader["additionalProperties"] == false
+	for _, name := range sortedKeys(writerProps) {
+		writerProp, _ := writerProps[name].(map[string]interface{})
+		readerProp, ok := readerProps[name].(map[string]interface{})
+		if !ok {
+			if closed {
+				problems = append(problems, fmt.Sprintf("%s/%s: property was removed", path, name))
+			}
+			continue
+		}
+		problems = append(problems, checkSchemaCompatibility(readerProp, writerProp, path+"/"+name)...)
+	}
+
+	readerItems, readerOk := reader["items"].(map[string]interface{})
+	writerItems, writerOk := writer["items"].(map[string]interface{})
+	if readerOk && writerOk {
+		problems = append(problems, checkSchemaCompatibility(readerItems, writerItems, path+"/items")...)
+	}
+
+	return problems
+}
+
+func schemaTypes(schema map[string]interface{}) map[string]bool {
+	types := make(map[string]bool)
+	switch t := schema["type"].(type) {
+	case string:
+		types[t] = true
+	case []interface{}:
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				types[s] = true
+			}
+		}
+	}
+	return types
+}
+
+func stringSet(value interface{}) map[string]bool {
+	set := make(map[string]bool)
+	items, _ := value.([]interface{})
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			set[s] = true
+		}
+	}
+	return set
+}
+
+func containsValue(values []interface{}, value interface{}) bool {
+	for _, v := range values {
+		if reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"ProjectMessageService/internal/repository"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func mustSchema(t *testing.T, s string) map[string]interface{} {
+	var schema map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(s), &schema))
+	return schema
+}
+
+func TestSchemaCompatibility(t *testing.T) {
+	v1 := mustSchema(t, `{
+		"type": "object",
+		"required": ["user_id"],
+		"properties": {
+			"user_id": {"type": "integer"},
+			"level": {"enum": ["info", "warn"]}
+		}
+	}`)
+
+	optionalField := mustSchema(t, `{
+		"type": "object",
+		"required": ["user_id"],
+		"properties": {
+			"user_id": {"type": "number"},
+			"level": {"enum": ["info", "warn", "error"]},
+			"text": {"type": "string"}
+		}
+	}`)
+	require.Empty(t, checkCompatibility(CompatibilityBackward, v1, optionalField))
+	require.Len(t, checkCompatibility(CompatibilityForward, v1, optionalField), 2)
+
+	requiredField := mustSchema(t, `{
+		"type": "object",
+		"required": ["user_id", "text"],
+		"properties": {
+			"user_id": {"type": "integer"},
+			"text": {"type": "string"}
+		}
+	}`)
+	require.Equal(t, []string{`/: field "text" became required`}, checkCompatibility(CompatibilityBackward, v1, requiredField))
+	require.Empty(t, checkCompatibility(CompatibilityForward, v1, requiredField))
+	require.NotEmpty(t, checkCompatibility(CompatibilityFull, v1, requiredField))
+	require.Empty(t, checkCompatibility(CompatibilityNone, v1, requiredField))
+}
+
+func TestTopicCompatibility(t *testing.T) {
+	mode, err := topicCompatibility("", nil)
+	require.NoError(t, err)
+	require.Equal(t, CompatibilityBackward, mode)
+
+	mode, err = topicCompatibility(CompatibilityFull, nil)
+	require.NoError(t, err)
+	require.Equal(t, CompatibilityFull, mode)
+
+	// Режим последующих версий берется из сохраненной схемы и не может быть изменен запросом
+	latest := &repository.TopicSchema{Topic: "message", Version: 2, Compatibility: CompatibilityFull}
+	mode, err = topicCompatibility("", latest)
+	require.NoError(t, err)
+	require.Equal(t, CompatibilityFull, mode)
+
+	mode, err = topicCompatibility(CompatibilityFull, latest)
+	require.NoError(t, err)
+	require.Equal(t, CompatibilityFull, mode)
+
+	_, err = topicCompatibility(CompatibilityNone, latest)
+	require.ErrorIs(t, err, ErrCompatibilityChange)
+}
@@ -2,122 +2,508 @@ package service
 
 import (
 	"ProjectMessageService/config"
+	"ProjectMessageService/internal/loggers"
+	"ProjectMessageService/internal/metrics"
+	"ProjectMessageService/internal/processor"
 	"ProjectMessageService/internal/repository"
+	"ProjectMessageService/internal/tracing"
 	"ProjectMessageService/internal/utils"
 	"context"
+	"encoding/json"
+	"errors"
+	"mime"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/segmentio/kafka-go"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type MessageService struct {
 	repo        *repository.Repository
 	kafkaWriter *kafka.Writer
 	kafkaReader *kafka.Reader
+	webhooks    *WebhookDispatcher
+	schemas     *SchemaRegistry
+	processors  *processor.Registry
+	router      *Router
+	ttls        topicTTLs
+	retention   retentionPolicies
+	priorities  map[string]map[string]int
+	workers     map[string]int
+	notifier    *topicNotifier
+	consumers   *consumerTracker
+	consumersMu sync.Mutex // Защищает consumersWG.Add от гонки с WaitConsumers при перезагрузке MESSAGE_TYPES
+	consumersWG sync.WaitGroup
+	waiting     bool // WaitConsumers вызван, новые консьюмеры не запускаются
+	jobs        *reprocessJobs
 	app         *config.Application
 }
 
-func NewMessageService(repo *repository.Repository, kafkaWriter *kafka.Writer, app *config.Application) *MessageService {
-	return &MessageService{repo: repo, kafkaWriter: kafkaWriter, app: app}
+// NewMessageService создает сервис и добавляет в конец конвейеров processors шаги route (правила маршрутизации)
+// и store (сохранение результата конвейера). Конвейеры из PROCESSORS настраиваются после создания сервиса.
+func NewMessageService(repo *repository.Repository, kafkaWriter *kafka.Writer, webhooks *WebhookDispatcher, schemas *SchemaRegistry, processors *processor.Registry, app *config.Application) *MessageService {
+	s := &MessageService{repo: repo, kafkaWriter: kafkaWriter, webhooks: webhooks, schemas: schemas, processors: processors, router: NewRouter(repo), notifier: newTopicNotifier(), consumers: newConsumerTracker(), jobs: newReprocessJobs(), app: app}
+	processors.RegisterFinalStep("route", processor.Func(s.routeStep))
+	processors.RegisterFinalStep("store", processor.Func(s.storeStep))
+	return s
+}
+
+// NewProcessorRegistry создает реестр конвейеров со встроенными шагами, шагом validate (проверка payload
+// по схеме топика) и middleware перехвата паники, журналирования и метрик.
+func NewProcessorRegistry(schemas *SchemaRegistry, app *config.Application) *processor.Registry {
+	registry := processor.NewRegistry()
+	registry.RegisterStep("validate", processor.Func(func(ctx context.Context, env *processor.Envelope) error {
+		return schemas.Validate(ctx, env.Message)
+	}))
+	registry.Use(processor.Metrics(), processor.Logging(app.Log), processor.Recover(app.Log))
+	return registry
+}
+
+// ValidateMessage проверяет payload сообщения по схеме топика.
+func (s *MessageService) ValidateMessage(ctx context.Context, message utils.Message) error {
+	return s.schemas.Validate(ctx, message)
+}
+
+func (s *MessageService) RegisterSchema(ctx context.Context, topic string, schema json.RawMessage, compatibility string) (repository.TopicSchema, error) {
+	return s.schemas.Register(ctx, topic, schema, compatibility)
+}
+
+func (s *MessageService) GetSchema(ctx context.Context, topic string) (repository.TopicSchema, error) {
+	return s.schemas.Latest(ctx, topic)
 }
 
 func (s *MessageService) SaveMessage(ctx context.Context, message utils.Message) error {
+	ctx, span := tracing.Tracer().Start(ctx, "MessageService.SaveMessage",
+		trace.WithAttributes(semconv.MessagingDestinationName(message.Topic)))
+	defer span.End()
+
+	message = s.withExpiry(message, time.Now())
 	if err := s.repo.SaveMessage(ctx, message); err != nil {
-		s.app.Log.Error("Ошибка сохранения сообщения:", err)
+		s.app.Log.WithCtx(ctx).Error("Ошибка сохранения сообщения:", err)
+		tracing.RecordError(span, err)
 		return err
 	}
 
-	topic, _ := getTopicAndGroup(message.Topic)
+	if err := s.publish(ctx, s.kafkaMessage(message)); err != nil {
+		metrics.MessageFailed(message.Topic, metrics.StagePublish)
+		tracing.RecordError(span, err)
+		return err
+	}
 
-	err := s.kafkaWriter.WriteMessages(ctx, kafka.Message{
-		Topic: topic,
-		Key:   []byte(message.Topic),
-		Value: []byte(message.Message),
-	})
+	metrics.MessagePublished(message.Topic)
+	return nil
+}
+
+// SaveMessages сохраняет пакет сообщений одной транзакцией и публикует их в Kafka одним вызовом WriteMessages.
+// Сообщения должны быть проверены заранее, результат возвращается по каждому сообщению.
+func (s *MessageService) SaveMessages(ctx context.Context, messages []utils.Message) ([]utils.MessageResult, error) {
+	results, _, err := s.SaveMessageBatch(ctx, messages, nil)
+	return results, err
+}
+
+// SaveMessageBatch сохраняет сообщения и отложенные сообщения scheduled одной транзакцией, затем публикует
+// сообщения в Kafka одним вызовом WriteMessages. Если транзакция не выполнена, не сохраняется ни одно сообщение.
+func (s *MessageService) SaveMessageBatch(ctx context.Context, messages []utils.Message, scheduled []repository.CreateScheduledMessageParams) ([]utils.MessageResult, []repository.ScheduledMessage, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "MessageService.SaveMessages",
+		trace.WithAttributes(semconv.MessagingBatchMessageCount(len(messages)+len(scheduled))))
+	defer span.End()
+
+	now := time.Now()
+	messages = append([]utils.Message(nil), messages...)
+	for i := range messages {
+		messages[i] = s.withExpiry(messages[i], now)
+	}
+
+	saved, savedScheduled, err := s.repo.SaveMessageBatch(ctx, messages, scheduled)
+	if err != nil {
+		s.app.Log.WithCtx(ctx).Error("Ошибка сохранения пакета сообщений:", err)
+		tracing.RecordError(span, err)
+		return nil, nil, err
+	}
+	for _, item := range savedScheduled {
+		s.app.Log.WithCtx(ctx).Infof("Сообщение %d топика %s будет опубликовано в %s", item.ID, item.Topic, item.DeliverAt.Format(time.RFC3339))
+	}
+	if len(messages) == 0 {
+		return nil, savedScheduled, nil
+	}
+
+	kafkaMessages := make([]kafka.Message, len(messages))
+	results := make([]utils.MessageResult, len(messages))
+	for i, message := range messages {
+		kafkaMessages[i] = s.kafkaMessage(message)
+		results[i] = utils.MessageResult{Topic: message.Topic, ID: saved[i].ID, Status: utils.BatchStatusAccepted}
+		if !saved[i].Inserted {
+			results[i].Status = utils.BatchStatusDuplicate
+		}
+	}
+
+	if err = s.publish(ctx, kafkaMessages...); err != nil {
+		s.app.Log.WithCtx(ctx).Errorf("Не удалось опубликовать пакет сообщений: %v", err)
+		markPublishFailures(results, err)
+	}
 
+	for _, result := range results {
+		if result.Status == utils.BatchStatusFailed {
+			metrics.MessageFailed(result.Topic, metrics.StagePublish)
+		} else {
+			metrics.MessagePublished(result.Topic)
+		}
+	}
+
+	return results, savedScheduled, nil
+}
+
+// markPublishFailures отмечает неудачными результаты сообщений, которые не удалось записать в Kafka.
+// kafka.WriteErrors содержит ошибку для каждого сообщения пакета, любая другая ошибка относится ко всем.
+func markPublishFailures(results []utils.MessageResult, err error) {
+	var writeErrors kafka.WriteErrors
+	if errors.As(err, &writeErrors) {
+		for i, writeErr := range writeErrors {
+			if writeErr != nil {
+				results[i].Status = utils.BatchStatusFailed
+				results[i].Error = writeErr.Error()
+			}
+		}
+		return
+	}
+	for i := range results {
+		results[i].Status = utils.BatchStatusFailed
+		results[i].Error = err.Error()
+	}
+}
+
+// publish записывает сообщения в Kafka, передавая в заголовках контекст трассировки.
+func (s *MessageService) publish(ctx context.Context, messages ...kafka.Message) error {
+	ctx, span := tracing.Tracer().Start(ctx, "kafka.produce",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(semconv.MessagingSystemKafka, semconv.MessagingBatchMessageCount(len(messages))))
+	defer span.End()
+
+	for i := range messages {
+		tracing.InjectKafkaHeaders(ctx, &messages[i])
+		injectLogHeaders(ctx, &messages[i])
+	}
+
+	err := s.kafkaWriter.WriteMessages(ctx, messages...)
+	if err != nil {
+		tracing.RecordError(span, err)
+	}
 	return err
 }
 
-func (s *MessageService) GetStats(ctx context.Context, topic utils.Messages) (int, error) {
-	return s.repo.GetProcessedMessagesCount(ctx, topic)
+// Служебные заголовки Kafka.
+const (
+	contentTypeHeader = "content-type" // тип содержимого сообщения
+	requestIDHeader   = "x-request-id" // идентификатор HTTP-запроса, опубликовавшего сообщение
+	usernameHeader    = "x-username"   // пользователь, опубликовавший сообщение
+)
+
+// injectLogHeaders передает в заголовках сообщения идентификатор запроса и пользователя,
+// чтобы записи журнала консьюмера можно было связать с исходным запросом.
+func injectLogHeaders(ctx context.Context, msg *kafka.Message) {
+	carrier := tracing.HeaderCarrier{Headers: &msg.Headers}
+	if requestID := loggers.RequestID(ctx); requestID != "" {
+		carrier.Set(requestIDHeader, requestID)
+	}
+	if username := loggers.Username(ctx); username != "" {
+		carrier.Set(usernameHeader, username)
+	}
+}
+
+// extractKafkaContext восстанавливает из заголовков сообщения контекст трассировки,
+// идентификатор запроса и пользователя.
+func extractKafkaContext(ctx context.Context, msg kafka.Message) context.Context {
+	ctx = tracing.ExtractKafkaHeaders(ctx, msg)
+	for _, header := range msg.Headers {
+		switch header.Key {
+		case requestIDHeader:
+			ctx = loggers.WithRequestID(ctx, string(header.Value))
+		case usernameHeader:
+			ctx = loggers.WithUsername(ctx, string(header.Value))
+		}
+	}
+	return ctx
+}
+
+func newKafkaMessage(message utils.Message) kafka.Message {
+	topic, _ := getTopicAndGroup(message.Topic)
+
+	headers := make([]kafka.Header, 0, len(message.Headers)+2)
+	for key, value := range message.Headers {
+		if strings.EqualFold(key, contentTypeHeader) || strings.EqualFold(key, expiresAtHeader) {
+			continue
+		}
+		headers = append(headers, kafka.Header{Key: key, Value: []byte(value)})
+	}
+	headers = append(headers, kafka.Header{Key: contentTypeHeader, Value: []byte(message.GetContentType())})
+	if message.ExpiresAt != nil {
+		headers = append(headers, kafka.Header{Key: expiresAtHeader, Value: []byte(message.ExpiresAt.UTC().Format(time.RFC3339Nano))})
+	}
+
+	return kafka.Message{
+		Topic:   topic,
+		Key:     []byte(message.PartitionKey()),
+		Value:   []byte(message.Content()),
+		Headers: headers,
+	}
+}
+
+// messageKey возвращает ключ сообщения Kafka. Сообщения без ключа публикуются с ключом-именем типа
+// (см. utils.Message.PartitionKey), для них возвращается пустая строка.
+func messageKey(msg kafka.Message) string {
+	if key := string(msg.Key); key != strings.Split(msg.Topic, "-")[0] {
+		return key
+	}
+	return ""
+}
+
+// messageFromKafka восстанавливает сообщение из записи Kafka. Тело JSON-типа считается payload.
+func messageFromKafka(msg kafka.Message) utils.Message {
+	parts := strings.Split(msg.Topic, "-")
+	message := utils.Message{Topic: parts[0], Priority: priorityFromTopic(msg.Topic)}
+
+	message.Key = messageKey(msg)
+	for _, header := range msg.Headers {
+		if header.Key == contentTypeHeader {
+			message.ContentType = string(header.Value)
+			continue
+		}
+		if header.Key == expiresAtHeader {
+			if expiresAt, err := time.Parse(time.RFC3339Nano, string(header.Value)); err == nil {
+				message.ExpiresAt = &expiresAt
+			}
+			continue
+		}
+		if header.Key == requestIDHeader || header.Key == usernameHeader || tracing.IsPropagationHeader(header.Key) {
+			continue
+		}
+		if message.Headers == nil {
+			message.Headers = make(map[string]string)
+		}
+		message.Headers[header.Key] = string(header.Value)
+	}
+
+	if isJSONContentType(message.ContentType) && json.Valid(msg.Value) {
+		message.Payload = msg.Value
+	} else {
+		message.Message = string(msg.Value)
+	}
+	return message
+}
+
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == utils.ContentTypeJSON || strings.HasSuffix(mediaType, "+json")
 }
 
 func NewKafkaWriter(cfg config.Config) *kafka.Writer {
 	return &kafka.Writer{
-		Addr:         kafka.TCP(cfg.KafkaURL),
-		Balancer:     &kafka.LeastBytes{},
+		Addr:         kafka.TCP(cfg.KafkaBrokers...),
+		Balancer:     &kafka.Hash{},    // Сообщения с одинаковым ключом попадают в один раздел
 		RequiredAcks: kafka.RequireAll, // Подтверждение от всех реплик
 	}
 }
 
+// ConsumeMessages запускает чтение топиков Kafka до отмены ctx. Топики, консьюмеры которых уже работают,
+// пропускаются, поэтому метод можно вызывать повторно для добавленных типов сообщений.
+// Отмена ctx прерывает только ожидание новых сообщений: текущее сообщение обрабатывается до конца.
+// У типов с уровнями приоритета (PRIORITIES) каждый уровень читается из своего топика, а сообщения
+// обрабатываются по очереди с учетом весов уровней. Сообщения обрабатывает пул из CONSUMER_WORKERS обработчиков
+// с сохранением порядка для каждого ключа, смещения фиксируются только после успешной обработки.
+// После вызова WaitConsumers новые консьюмеры не запускаются.
 func (s *MessageService) ConsumeMessages(ctx context.Context, cfg config.Config, messageTypes []string) {
-	var message utils.Message
+	s.consumersMu.Lock()
+	defer s.consumersMu.Unlock()
+	if s.waiting {
+		return
+	}
 	for _, messageType := range messageTypes {
+		if !s.consumers.start(messageType, s.messagePriorities(messageType)) {
+			continue
+		}
+		s.consumersWG.Add(1)
 		go func(messageType string) {
-			reader := NewKafkaReader(cfg, messageType)
-			defer func(reader *kafka.Reader) {
-				_ = reader.Close()
-			}(reader)
-
-			for {
-				msg, err := reader.ReadMessage(ctx)
-				if err != nil {
-					s.app.Log.Errorf("Не удалось прочитать сообщение: %v", err)
-					continue
-				}
-				parts := strings.Split(msg.Topic, "-")
-				message.Topic = parts[0]
-				message.Message = string(msg.Value)
-
-				s.app.Log.Infof("msg.Topic %v, msg.Headers %v, msg.Partition %v, msg.Offset %v\n", msg.Topic, msg.Headers, msg.Partition, msg.Offset)
-
-				// Обработка сообщения
-				err = s.processMessage(ctx, message)
-				if err != nil {
-					s.app.Log.Errorf("Ошибка: %v", err)
-					return
-				}
-			}
+			defer s.consumersWG.Done()
+			defer s.consumers.stop(messageType)
+			s.runConsumer(ctx, cfg, messageType)
 		}(messageType)
 	}
 }
 
+// fetchMessages читает сообщения уровня приоритета и передает их консьюмеру до отмены ctx.
+func (s *MessageService) fetchMessages(ctx context.Context, messageType string, level *priorityLevel) {
+	defer close(level.messages)
+	for {
+		msg, err := level.reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			s.app.Log.Errorf("Не удалось прочитать сообщение: %v", err)
+			topic, _ := priorityTopicAndGroup(messageType, level.priority)
+			s.consumers.failed(topic, err)
+			metrics.MessageFailed(messageType, metrics.StageConsume)
+			continue
+		}
+		select {
+		case level.messages <- msg:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// consumeMessage обрабатывает сообщение, прочитанное из Kafka. Ошибка обработки останавливает консьюмер типа.
+func (s *MessageService) consumeMessage(messageType string, msg kafka.Message) error {
+	s.consumers.received(msg)
+	message := messageFromKafka(msg)
+
+	msgCtx, span := tracing.Tracer().Start(extractKafkaContext(context.Background(), msg), "kafka.consume",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			semconv.MessagingDestinationName(msg.Topic),
+			semconv.MessagingKafkaDestinationPartition(msg.Partition),
+			semconv.MessagingKafkaMessageOffset(int(msg.Offset)),
+		))
+	defer span.End()
+
+	s.app.Log.WithCtx(msgCtx).Infof("msg.Topic %v, msg.Headers %v, msg.Partition %v, msg.Offset %v\n", msg.Topic, msg.Headers, msg.Partition, msg.Offset)
+
+	// Обработка сообщения
+	id, err := s.processMessage(msgCtx, message)
+	if err != nil {
+		s.app.Log.WithCtx(msgCtx).Errorf("Ошибка: %v", err)
+		// Сообщение, не захваченное этой доставкой, может обрабатывать другая
+		if id != 0 {
+			if markErr := s.repo.MarkMessageAsFailed(msgCtx, message.Topic, id, err); markErr != nil {
+				s.app.Log.WithCtx(msgCtx).Errorf("Не удалось отметить сообщение как необработанное: %v", markErr)
+			}
+		}
+		s.consumers.failed(msg.Topic, err)
+		metrics.MessageFailed(messageType, metrics.StageProcess)
+		tracing.RecordError(span, err)
+		return err
+	}
+	metrics.MessageConsumed(messageType)
+	return nil
+}
+
+// WaitConsumers ждет остановки всех консьюмеров, запущенных ConsumeMessages. Вызывается при остановке сервиса.
+func (s *MessageService) WaitConsumers() {
+	s.consumersMu.Lock()
+	s.waiting = true
+	s.consumersMu.Unlock()
+	s.consumersWG.Wait()
+}
+
 // getTopicAndGroup для определения топика и группы.
 func getTopicAndGroup(messageType string) (string, string) {
 	return messageType + "-topic", messageType + "-group"
 }
 
-func NewKafkaReader(cfg config.Config, messageType string) *kafka.Reader {
-	topic, group := getTopicAndGroup(messageType)
+// NewKafkaReader создает читателя топика уровня приоритета типа сообщений. Смещения фиксируются явно после обработки.
+func NewKafkaReader(cfg config.Config, messageType, priority string) *kafka.Reader {
+	topic, group := priorityTopicAndGroup(messageType, priority)
 
 	return kafka.NewReader(kafka.ReaderConfig{
-		Brokers: []string{cfg.KafkaURL},
+		Brokers: cfg.KafkaBrokers,
 		Topic:   topic,
 		GroupID: group,
 	})
 }
 
-func (s *MessageService) processMessage(ctx context.Context, msg utils.Message) error {
+// readerMetricsName - имя читателя в метриках Kafka: тип сообщений, для уровней high и low - с уровнем.
+func readerMetricsName(messageType, priority string) string {
+	if priority == utils.PriorityNormal {
+		return messageType
+	}
+	return messageType + "-" + priority
+}
+
+// storeStep - шаг конвейера store: сохраняет результат конвейера, который затем выдает POST /topics/:topic/consume.
+func (s *MessageService) storeStep(ctx context.Context, env *processor.Envelope) error {
+	return s.repo.SaveMessageOutput(ctx, env.Message.Topic, env.ID, env.Message)
+}
+
+// processingLease - на сколько сообщение захватывается для обработки. Если обработчик остановился,
+// не сняв захват, сообщение можно обработать повторно по истечении этого времени.
+const processingLease = 5 * time.Minute
+
+// processMessage сохраняет и обрабатывает сообщение. Возвращает id сообщения, если оно было захвачено
+// для обработки, иначе 0: захват снимает MarkMessageAsFailed при ошибке.
+func (s *MessageService) processMessage(ctx context.Context, msg utils.Message) (int, error) {
 	if err := s.repo.SaveMessage(ctx, msg); err != nil {
-		s.app.Log.Errorf("Ошибка сохранения сообщения: %v", err)
-		return err
+		s.app.Log.WithCtx(ctx).Errorf("Ошибка сохранения сообщения: %v", err)
+		return 0, err
 	}
 
 	// Обработка сообщения
-	key, err := s.repo.ContentMessagesKey(context.Background(), msg)
+	key, err := s.repo.ContentMessagesKey(ctx, msg)
 	if err != nil {
-		s.app.Log.Errorf("Failed to select messages key: %v", err)
-		return err
+		s.app.Log.WithCtx(ctx).Errorf("Failed to select messages key: %v", err)
+		return 0, err
+	}
+
+	// Истекшее сообщение не обрабатывается и не доставляется подписчикам
+	if msg.Expired(time.Now()) {
+		if err = s.repo.MarkMessageAsExpired(ctx, msg.Topic, key); err != nil {
+			s.app.Log.WithCtx(ctx).Errorf("Failed to mark message as expired: %v", err)
+			return 0, err
+		}
+		s.app.Log.WithCtx(ctx).Infof("Срок жизни сообщения %d топика %s истек в %s, обработка пропущена",
+			key, msg.Topic, msg.ExpiresAt.Format(time.RFC3339))
+		metrics.MessageExpired(msg.Topic)
+		return 0, nil
+	}
+
+	// Повторно доставленное из Kafka сообщение обрабатывается, только если его обработка не завершена
+	// или запрошена повторно (ReprocessMessages, ResetOffsets с reprocess) и его не обрабатывает другая доставка
+	attempts, ok, err := s.repo.BeginProcessing(ctx, msg.Topic, key, processingLease)
+	if err != nil {
+		s.app.Log.WithCtx(ctx).Errorf("Failed to start processing: %v", err)
+		return 0, err
+	}
+	if !ok {
+		s.app.Log.WithCtx(ctx).Infof("Сообщение %d топика %s уже обработано или обрабатывается, повторная доставка пропущена", key, msg.Topic)
+		return 0, nil
+	}
+	if attempts > 1 {
+		s.app.Log.WithCtx(ctx).Infof("Попытка обработки %d сообщения %d топика %s", attempts, key, msg.Topic)
+	}
+
+	// Конвейер топика: проверка, обогащение, преобразование, маршрутизация и сохранение результата
+	envelope := &processor.Envelope{ID: key, Attempt: attempts, Message: msg}
+	err = s.processors.Get(msg.Topic).Process(ctx, envelope)
+	dropped := errors.Is(err, processor.ErrDrop)
+	if err != nil && !dropped {
+		return key, err
 	}
 
 	// Обновление состояния сообщения в базе данных
-	err = s.repo.MarkMessageAsProcessed(context.Background(), msg, key)
+	err = s.repo.MarkMessageAsProcessed(ctx, msg, key)
 	if err != nil {
-		s.app.Log.Errorf("Failed to mark message as processed: %v", err)
-		return err
+		s.app.Log.WithCtx(ctx).Errorf("Failed to mark message as processed: %v", err)
+		return key, err
+	}
+	if dropped {
+		return key, nil
 	}
 
-	s.app.Log.Infof("Processing message: %s Message written to topic %s", msg.Message, msg.Topic)
-	// Дополнительная логика обработки
-	return nil
+	s.app.Log.WithCtx(ctx).Infof("Processing message: %s Message written to topic %s", msg.Message, msg.Topic)
+
+	// Уведомление подписчиков топика и ожидающих HTTP-консьюмеров
+	s.notifier.notify(msg.Topic)
+	s.webhooks.Dispatch(WebhookEvent{
+		ID:          key,
+		Message:     envelope.Message,
+		ProcessedAt: time.Now(),
+	})
+	return key, nil
 }
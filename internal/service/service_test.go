@@ -0,0 +1,139 @@
+package service
+
+import (
+	"ProjectMessageService/config"
+	"ProjectMessageService/internal/loggers"
+	"ProjectMessageService/internal/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKafkaMessageRoundTrip(t *testing.T) {
+	message := utils.Message{
+		Topic:   "message",
+		Payload: json.RawMessage(`{"user_id":42,"text":"hi"}`),
+		Key:     "user-42",
+		Headers: map[string]string{"source": "billing"},
+	}
+
+	msg := newKafkaMessage(message)
+	require.Equal(t, "message-topic", msg.Topic)
+	require.Equal(t, "user-42", string(msg.Key))
+	require.Equal(t, message.Content(), string(msg.Value))
+
+	got := messageFromKafka(msg)
+	require.Equal(t, message.Topic, got.Topic)
+	require.Equal(t, message.Key, got.Key)
+	require.Equal(t, message.Headers, got.Headers)
+	require.Equal(t, utils.ContentTypeJSON, got.ContentType)
+	require.JSONEq(t, string(message.Payload), string(got.Payload))
+	require.Empty(t, got.Message)
+}
+
+func TestKafkaMessageDefaults(t *testing.T) {
+	message := utils.Message{Topic: "ping", Message: "Hello, world!"}
+
+	msg := newKafkaMessage(message)
+	require.Equal(t, "ping", string(msg.Key))
+
+	got := messageFromKafka(msg)
+	require.Equal(t, message.Message, got.Message)
+	require.Empty(t, got.Key)
+	require.Empty(t, got.Payload)
+	require.Nil(t, got.Headers)
+	require.Equal(t, utils.ContentTypeText, got.ContentType)
+}
+
+func TestKafkaMessageCarriesExpiry(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	message := utils.Message{Topic: "ping", Message: "Hello, world!", TTL: "5m"}.WithExpiry(now, time.Hour)
+	require.Equal(t, now.Add(5*time.Minute), *message.ExpiresAt)
+
+	got := messageFromKafka(newKafkaMessage(message))
+	require.Nil(t, got.Headers)
+	require.True(t, message.ExpiresAt.Equal(*got.ExpiresAt))
+	require.False(t, got.Expired(now))
+	require.True(t, got.Expired(now.Add(5*time.Minute)))
+
+	require.Nil(t, utils.Message{Topic: "ping"}.WithExpiry(now, 0).ExpiresAt)
+}
+
+func TestKafkaMessageCarriesRequestContext(t *testing.T) {
+	ctx := loggers.WithUsername(loggers.WithRequestID(context.Background(), "req-1"), "alice")
+	message := utils.Message{Topic: "ping", Message: "Hello, world!", Headers: map[string]string{"source": "billing"}}
+
+	msg := newKafkaMessage(message)
+	injectLogHeaders(ctx, &msg)
+
+	got := extractKafkaContext(context.Background(), msg)
+	require.Equal(t, "req-1", loggers.RequestID(got))
+	require.Equal(t, "alice", loggers.Username(got))
+	require.Equal(t, message.Headers, messageFromKafka(msg).Headers)
+}
+
+func TestConsumeMessagesAfterWaitConsumers(t *testing.T) {
+	s := &MessageService{consumers: newConsumerTracker()}
+	s.WaitConsumers()
+
+	// После начала остановки консьюмеры добавленных типов не запускаются
+	s.ConsumeMessages(context.Background(), config.Config{}, []string{"message"})
+	require.Empty(t, s.ConsumerStatuses())
+}
+
+func TestConsumerTrackerPriorities(t *testing.T) {
+	s := &MessageService{priorities: map[string]map[string]int{"message": {"high": 6, "normal": 3, "low": 1}}}
+	tracker := newConsumerTracker()
+	require.True(t, tracker.start("message", s.messagePriorities("message")))
+	require.True(t, tracker.start("ping", s.messagePriorities("ping")))
+	require.False(t, tracker.start("message", s.messagePriorities("message")))
+
+	// Сообщения и ошибки уровня приоритета отражаются в состоянии его топика
+	highTopic, highGroup := priorityTopicAndGroup("message", utils.PriorityHigh)
+	tracker.received(kafka.Message{Topic: highTopic, Partition: 2, Offset: 41})
+	tracker.failed(highTopic, errors.New("broker unavailable"))
+
+	statuses := tracker.statuses()
+	require.Len(t, statuses, 4)
+	require.Equal(t, []string{"high", "normal", "low", "normal"},
+		[]string{statuses[0].Priority, statuses[1].Priority, statuses[2].Priority, statuses[3].Priority})
+	require.Equal(t, highGroup, statuses[0].Group)
+	require.EqualValues(t, 41, statuses[0].LastOffset)
+	require.Equal(t, "broker unavailable", statuses[0].LastError)
+	require.EqualValues(t, -1, statuses[1].LastOffset)
+
+	tracker.stop("message")
+	require.ErrorContains(t, tracker.check(), highTopic)
+	require.True(t, tracker.start("message", s.messagePriorities("message")))
+	require.NoError(t, tracker.check())
+}
+
+func TestMarkPublishFailures(t *testing.T) {
+	newResults := func() []utils.MessageResult {
+		return []utils.MessageResult{
+			{Index: 0, Status: utils.BatchStatusAccepted},
+			{Index: 1, Status: utils.BatchStatusDuplicate},
+			{Index: 2, Status: utils.BatchStatusAccepted},
+		}
+	}
+
+	// Ошибки отдельных сообщений не затрагивают остальные
+	results := newResults()
+	markPublishFailures(results, kafka.WriteErrors{nil, errors.New("message too large"), nil})
+	require.Equal(t, utils.BatchStatusAccepted, results[0].Status)
+	require.Equal(t, utils.BatchStatusFailed, results[1].Status)
+	require.Equal(t, "message too large", results[1].Error)
+	require.Equal(t, utils.BatchStatusAccepted, results[2].Status)
+
+	results = newResults()
+	markPublishFailures(results, errors.New("kafka unavailable"))
+	for _, result := range results {
+		require.Equal(t, utils.BatchStatusFailed, result.Status)
+		require.Equal(t, "kafka unavailable", result.Error)
+	}
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"ProjectMessageService/internal/repository"
+	"context"
+	"time"
+)
+
+// StatsTotals - сводная статистика по всем топикам окна.
+type StatsTotals struct {
+	Total               int     `json:"total"`
+	Processed           int     `json:"processed"`
+	Pending             int     `json:"pending"`
+	Failed              int     `json:"failed"`
+	Expired             int     `json:"expired"`
+	ThroughputPerMinute float64 `json:"throughput_per_minute"`
+	ThroughputPerHour   float64 `json:"throughput_per_hour"`
+}
+
+// Stats - статистика топиков за окно [From, To).
+type Stats struct {
+	From   time.Time               `json:"from"`
+	To     time.Time               `json:"to"`
+	Bucket string                  `json:"bucket"`
+	Totals StatsTotals             `json:"totals"`
+	Topics []repository.TopicStats `json:"topics"`
+}
+
+// GetTopicStats возвращает статистику одного топика.
+func (s *MessageService) GetTopicStats(ctx context.Context, arg repository.StatsParams) (repository.TopicStats, error) {
+	return s.repo.GetTopicStats(ctx, arg)
+}
+
+// GetStats возвращает статистику перечисленных топиков и сумму по ним.
+func (s *MessageService) GetStats(ctx context.Context, arg repository.StatsParams, topics []string) (Stats, error) {
+	stats := Stats{From: arg.From, To: arg.To, Bucket: arg.Bucket, Topics: make([]repository.TopicStats, 0, len(topics))}
+	for _, topic := range topics {
+		arg.Topic = topic
+		topicStats, err := s.repo.GetTopicStats(ctx, arg)
+		if err != nil {
+			return stats, err
+		}
+		stats.Topics = append(stats.Topics, topicStats)
+
+		stats.Totals.Total += topicStats.Total
+		stats.Totals.Processed += topicStats.Processed
+		stats.Totals.Pending += topicStats.Pending
+		stats.Totals.Failed += topicStats.Failed
+		stats.Totals.Expired += topicStats.Expired
+		stats.Totals.ThroughputPerMinute += topicStats.ThroughputPerMinute
+		stats.Totals.ThroughputPerHour += topicStats.ThroughputPerHour
+	}
+	return stats, nil
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"ProjectMessageService/config"
+	"ProjectMessageService/internal/repository"
+	"ProjectMessageService/internal/utils"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	webhookSignatureHeader = "X-Webhook-Signature"
+	webhookTopicHeader     = "X-Webhook-Topic"
+	webhookAttemptHeader   = "X-Webhook-Attempt"
+
+	webhookQueueSize   = 1024
+	webhookWorkers     = 8                      // Количество одновременных доставок
+	webhookMaxAttempts = 5                      // Количество попыток доставки одного события
+	webhookBaseBackoff = 500 * time.Millisecond // Задержка перед второй попыткой, далее удваивается
+	webhookMaxFailures = 10                     // После стольких неудачных событий подряд вебхук отключается
+	webhookTimeout     = 10 * time.Second
+)
+
+// WebhookEvent - событие об обработке сообщения, отправляемое подписчикам.
+type WebhookEvent struct {
+	ID int `json:"id"`
+	utils.Message
+	ProcessedAt time.Time `json:"processed_at"`
+}
+
+// WebhookStore - хранилище подписок и истории доставок.
+type WebhookStore interface {
+	ListActiveWebhooks(ctx context.Context, topic string) ([]repository.Webhook, error)
+	CreateWebhookDelivery(ctx context.Context, arg repository.CreateWebhookDeliveryParams) error
+	MarkWebhookSucceeded(ctx context.Context, id int64) error
+	MarkWebhookFailed(ctx context.Context, id int64, maxFailures int) (bool, error)
+}
+
+// WebhookDispatcher доставляет события подписчикам в фоне.
+type WebhookDispatcher struct {
+	store       WebhookStore
+	client      *http.Client
+	app         *config.Application
+	queue       chan WebhookEvent
+	workers     int
+	maxAttempts int
+	baseBackoff time.Duration
+	maxFailures int
+}
+
+func NewWebhookDispatcher(store WebhookStore, app *config.Application) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		store:       store,
+		client:      &http.Client{Timeout: webhookTimeout},
+		app:         app,
+		queue:       make(chan WebhookEvent, webhookQueueSize),
+		workers:     webhookWorkers,
+		maxAttempts: webhookMaxAttempts,
+		baseBackoff: webhookBaseBackoff,
+		maxFailures: webhookMaxFailures,
+	}
+}
+
+// Dispatch ставит событие в очередь доставки. Если очередь переполнена, событие отбрасывается,
+// чтобы не блокировать обработку сообщений.
+func (d *WebhookDispatcher) Dispatch(event WebhookEvent) {
+	select {
+	case d.queue <- event:
+	default:
+		d.app.Log.Warnf("Очередь вебхуков переполнена, событие %d топика %s отброшено", event.ID, event.Topic)
+	}
+}
+
+// webhookDelivery - доставка события одному подписчику.
+type webhookDelivery struct {
+	hook  repository.Webhook
+	event WebhookEvent
+}
+
+// Run читает очередь событий до отмены контекста. Доставки выполняют webhookWorkers обработчиков,
+// Run возвращается только после завершения всех начатых доставок.
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	deliveries := make(chan webhookDelivery)
+	var workers sync.WaitGroup
+	for i := 0; i < d.workers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for delivery := range deliveries {
+				d.deliver(ctx, delivery.hook, delivery.event)
+			}
+		}()
+	}
+	defer func() {
+		close(deliveries)
+		workers.Wait()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if pending := len(d.queue); pending > 0 {
+				d.app.Log.Warnf("Остановка доставки вебхуков, не отправлено событий: %d", pending)
+			}
+			return
+		case event := <-d.queue:
+			d.handleEvent(ctx, event, deliveries)
+		}
+	}
+}
+
+func (d *WebhookDispatcher) handleEvent(ctx context.Context, event WebhookEvent, deliveries chan<- webhookDelivery) {
+	hooks, err := d.store.ListActiveWebhooks(ctx, event.Topic)
+	if err != nil {
+		d.app.Log.Errorf("Не удалось получить вебхуки топика %s: %v", event.Topic, err)
+		return
+	}
+
+	for _, hook := range hooks {
+		if hook.Filter != "" && !strings.Contains(event.Content(), hook.Filter) {
+			continue
+		}
+		select {
+		case deliveries <- webhookDelivery{hook: hook, event: event}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// deliver отправляет событие одному подписчику с повторами и экспоненциальной задержкой.
+func (d *WebhookDispatcher) deliver(ctx context.Context, hook repository.Webhook, event WebhookEvent) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		d.app.Log.Errorf("Не удалось сериализовать событие вебхука: %v", err)
+		return
+	}
+
+	backoff := d.baseBackoff
+	for attempt := 1; attempt <= d.maxAttempts; attempt++ {
+		statusCode, err := d.send(ctx, hook, body, attempt)
+
+		delivery := repository.CreateWebhookDeliveryParams{
+			WebhookID:  hook.ID,
+			MessageID:  event.ID,
+			Attempt:    attempt,
+			StatusCode: statusCode,
+			Success:    err == nil,
+		}
+		if err != nil {
+			delivery.Error = err.Error()
+		}
+		if recErr := d.store.CreateWebhookDelivery(ctx, delivery); recErr != nil {
+			d.app.Log.Errorf("Не удалось записать попытку доставки вебхука %d: %v", hook.ID, recErr)
+		}
+
+		if err == nil {
+			if err = d.store.MarkWebhookSucceeded(ctx, hook.ID); err != nil {
+				d.app.Log.Errorf("Не удалось обновить вебхук %d: %v", hook.ID, err)
+			}
+			return
+		}
+
+		d.app.Log.Warnf("Попытка %d доставки вебхука %d не удалась: %v", attempt, hook.ID, err)
+		if attempt == d.maxAttempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+
+	active, err := d.store.MarkWebhookFailed(ctx, hook.ID, d.maxFailures)
+	if err != nil {
+		d.app.Log.Errorf("Не удалось обновить вебхук %d: %v", hook.ID, err)
+		return
+	}
+	if !active {
+		d.app.Log.Warnf("Вебхук %d (%s) отключен после %d неудачных доставок подряд", hook.ID, hook.URL, d.maxFailures)
+	}
+}
+
+func (d *WebhookDispatcher) send(ctx context.Context, hook repository.Webhook, body []byte, attempt int) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookSignatureHeader, "sha256="+SignWebhookPayload(hook.Secret, body))
+	req.Header.Set(webhookTopicHeader, hook.Topic)
+	req.Header.Set(webhookAttemptHeader, strconv.Itoa(attempt))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// SignWebhookPayload возвращает HMAC-SHA256 тела запроса в шестнадцатеричном виде.
+func SignWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
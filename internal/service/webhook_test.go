@@ -0,0 +1,167 @@
+package service
+
+import (
+	"ProjectMessageService/config"
+	"ProjectMessageService/internal/repository"
+	"ProjectMessageService/internal/utils"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeWebhookStore struct {
+	mu         sync.Mutex
+	hooks      []repository.Webhook
+	deliveries []repository.CreateWebhookDeliveryParams
+	succeeded  int
+	failures   int
+}
+
+func (s *fakeWebhookStore) ListActiveWebhooks(_ context.Context, _ string) ([]repository.Webhook, error) {
+	return s.hooks, nil
+}
+
+func (s *fakeWebhookStore) CreateWebhookDelivery(_ context.Context, arg repository.CreateWebhookDeliveryParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deliveries = append(s.deliveries, arg)
+	return nil
+}
+
+func (s *fakeWebhookStore) MarkWebhookSucceeded(_ context.Context, _ int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.succeeded++
+	return nil
+}
+
+func (s *fakeWebhookStore) MarkWebhookFailed(_ context.Context, _ int64, maxFailures int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures++
+	return s.failures < maxFailures, nil
+}
+
+func newTestDispatcher(store WebhookStore) *WebhookDispatcher {
+	d := NewWebhookDispatcher(store, config.SetupApplication())
+	d.baseBackoff = time.Millisecond
+	return d
+}
+
+func TestWebhookDeliverySignedAndRetried(t *testing.T) {
+	secret := "0123456789abcdef"
+	event := WebhookEvent{ID: 7, Message: utils.Message{Topic: "ping", Message: "Hello, world!"}, ProcessedAt: time.Now()}
+
+	type request struct {
+		body      []byte
+		signature string
+	}
+	requests := make(chan request, 2)
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- request{body: body, signature: r.Header.Get(webhookSignatureHeader)}
+
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	store := &fakeWebhookStore{}
+	d := newTestDispatcher(store)
+	d.deliver(context.Background(), repository.Webhook{ID: 1, Topic: "ping", URL: srv.URL, Secret: secret}, event)
+
+	require.EqualValues(t, 2, atomic.LoadInt32(&calls))
+	close(requests)
+	for req := range requests {
+		require.Equal(t, "sha256="+SignWebhookPayload(secret, req.body), req.signature)
+
+		var got WebhookEvent
+		require.NoError(t, json.Unmarshal(req.body, &got))
+		require.Equal(t, event.ID, got.ID)
+		require.Equal(t, event.Message.Message, got.Message.Message)
+	}
+	require.Len(t, store.deliveries, 2)
+	require.False(t, store.deliveries[0].Success)
+	require.Equal(t, http.StatusInternalServerError, store.deliveries[0].StatusCode)
+	require.True(t, store.deliveries[1].Success)
+	require.Equal(t, 1, store.succeeded)
+	require.Zero(t, store.failures)
+}
+
+func TestWebhookDisabledAfterRepeatedFailures(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	store := &fakeWebhookStore{}
+	d := newTestDispatcher(store)
+	d.maxAttempts = 2
+	d.maxFailures = 3
+
+	hook := repository.Webhook{ID: 1, Topic: "ping", URL: srv.URL, Secret: "0123456789abcdef"}
+	for i := 0; i < d.maxFailures; i++ {
+		d.deliver(context.Background(), hook, WebhookEvent{ID: i, Message: utils.Message{Topic: "ping"}})
+	}
+
+	require.Len(t, store.deliveries, d.maxAttempts*d.maxFailures)
+	require.Equal(t, d.maxFailures, store.failures)
+	require.Zero(t, store.succeeded)
+}
+
+func TestWebhookRunWaitsForDeliveries(t *testing.T) {
+	var active, maxActive int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&active, 1)
+		for {
+			m := atomic.LoadInt32(&maxActive)
+			if n <= m || atomic.CompareAndSwapInt32(&maxActive, m, n) {
+				break
+			}
+		}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	store := &fakeWebhookStore{}
+	for i := 1; i <= 5; i++ {
+		store.hooks = append(store.hooks, repository.Webhook{ID: int64(i), Topic: "ping", URL: srv.URL, Secret: "0123456789abcdef"})
+	}
+	d := newTestDispatcher(store)
+	d.workers = 2
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		d.Run(ctx)
+		close(done)
+	}()
+	d.Dispatch(WebhookEvent{ID: 1, Message: utils.Message{Topic: "ping"}})
+
+	// Одновременно выполняется не больше d.workers доставок
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&active) == 2 }, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	require.EqualValues(t, 2, atomic.LoadInt32(&maxActive))
+
+	// Run возвращается после того, как начатые доставки записали результат
+	cancel()
+	<-done
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	require.Len(t, store.deliveries, 2)
+}
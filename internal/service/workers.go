@@ -0,0 +1,306 @@
+package service
+
+import (
+	"ProjectMessageService/config"
+	"ProjectMessageService/internal/metrics"
+	"ProjectMessageService/internal/utils"
+	"context"
+	"hash/fnv"
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// SetConsumerWorkers задает число обработчиков консьюмеров топиков (CONSUMER_WORKERS). Вызывается до ConsumeMessages.
+func (s *MessageService) SetConsumerWorkers(workers map[string]int) {
+	s.workers = workers
+}
+
+// levelReader читает сообщения уровня приоритета и фиксирует их смещения (*kafka.Reader).
+type levelReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// runConsumer читает уровни приоритета типа сообщений и обрабатывает сообщения пулом обработчиков
+// до отмены ctx или ошибки обработки (см. consumeLevels).
+func (s *MessageService) runConsumer(ctx context.Context, cfg config.Config, messageType string) {
+	levels := make([]*priorityLevel, 0, len(utils.Priorities))
+	for _, level := range s.messagePriorities(messageType) {
+		level := level
+		reader := NewKafkaReader(cfg, messageType, level.priority)
+		name := readerMetricsName(messageType, level.priority)
+		metrics.RegisterKafkaReader(name, reader)
+		defer metrics.UnregisterKafkaReader(name)
+		level.reader = reader
+		levels = append(levels, &level)
+	}
+
+	s.consumeLevels(ctx, messageType, levels, func(msg kafka.Message) error {
+		return s.consumeMessage(messageType, msg)
+	})
+}
+
+// consumeLevels читает сообщения уровней приоритета и обрабатывает их process пулом обработчиков
+// до отмены ctx или ошибки обработки (см. runKeyedWorkers). При остановке чтение прекращается сразу,
+// а читатели закрываются только после завершения обработчиков и последней фиксации смещений.
+func (s *MessageService) consumeLevels(ctx context.Context, messageType string, levels []*priorityLevel, process func(kafka.Message) error) {
+	// Чтение уровней прекращается и при остановке консьюмера из-за ошибки обработки
+	consumerCtx, stopConsumer := context.WithCancel(ctx)
+	defer stopConsumer()
+
+	var fetchers sync.WaitGroup
+	readers := make(map[string]levelReader, len(levels))
+	for _, level := range levels {
+		topic, _ := priorityTopicAndGroup(messageType, level.priority)
+		readers[topic] = level.reader
+		level.messages = make(chan kafka.Message)
+		fetchers.Add(1)
+		go func(level *priorityLevel) {
+			defer fetchers.Done()
+			s.fetchMessages(consumerCtx, messageType, level)
+		}(level)
+	}
+
+	workers := s.workers[messageType]
+	if workers < 1 {
+		workers = 1
+	}
+	drain := newWeightedDrain(levels)
+	runKeyedWorkers(consumerCtx, stopConsumer, workers,
+		func(ctx context.Context) (kafka.Message, bool) {
+			_, msg, ok := drain.next(ctx)
+			return msg, ok
+		},
+		process,
+		func(commits map[string][]kafka.Message) {
+			for topic, messages := range commits {
+				// Фиксация не прерывается остановкой, чтобы обработанные сообщения не были прочитаны повторно
+				if err := readers[topic].CommitMessages(context.Background(), messages...); err != nil {
+					s.app.Log.Errorf("Не удалось зафиксировать смещения топика %s: %v", topic, err)
+				}
+			}
+		})
+
+	stopConsumer()
+	fetchers.Wait()
+	for _, level := range levels {
+		_ = level.reader.Close()
+	}
+}
+
+// runKeyedWorkers читает сообщения next и обрабатывает их process пулом из workers обработчиков до отмены ctx
+// или окончания сообщений. Сообщения с одинаковым ключом обрабатывает один обработчик в порядке чтения,
+// сообщения без ключа распределяются по обработчикам по очереди. Сообщение передается обработчику, только когда
+// тот свободен, поэтому порядок уровней приоритета, выбранный next, сохраняется.
+// Ошибка обработки вызывает stop. Смещение раздела передается в commit, только когда успешно обработаны
+// все прочитанные до него сообщения раздела, поэтому после ошибки или остановки необработанные сообщения
+// будут прочитаны повторно. Возвращается после завершения обработчиков и последней фиксации.
+func runKeyedWorkers(ctx context.Context, stop context.CancelFunc, workers int,
+	next func(context.Context) (kafka.Message, bool), process func(kafka.Message) error, commit func(map[string][]kafka.Message)) {
+	tracker := newOffsetTracker()
+	pool := newKeyedWorkers(workers)
+	pool.start(func(msg kafka.Message) bool {
+		// После ошибки переданные обработчикам сообщения не обрабатываются и будут прочитаны повторно
+		if ctx.Err() != nil {
+			return false
+		}
+		if err := process(msg); err != nil {
+			stop()
+			return false
+		}
+		return true
+	}, func(done <-chan kafka.Message) {
+		commitProcessed(done, tracker, commit)
+	})
+
+	for {
+		msg, ok := next(ctx)
+		if !ok {
+			break
+		}
+		tracker.fetched(msg)
+		if !pool.dispatch(ctx, msg) {
+			break
+		}
+	}
+	// Обработчики завершают текущие сообщения, затем фиксируются последние смещения
+	pool.stop()
+}
+
+// commitProcessed передает в commit смещения обработанных сообщений. Завершения, накопившиеся за время
+// предыдущей фиксации, фиксируются вместе.
+func commitProcessed(done <-chan kafka.Message, tracker *offsetTracker, commit func(map[string][]kafka.Message)) {
+	for msg := range done {
+		tracker.completed(msg)
+		for drained := false; !drained; {
+			select {
+			case msg, ok := <-done:
+				if !ok {
+					drained = true
+					break
+				}
+				tracker.completed(msg)
+			default:
+				drained = true
+			}
+		}
+
+		if commits := tracker.commits(); len(commits) > 0 {
+			commit(commits)
+		}
+	}
+}
+
+// keyedWorkers - пул обработчиков, распределяющий сообщения по ключу Kafka.
+type keyedWorkers struct {
+	queues    []chan kafka.Message
+	next      int // Обработчик следующего сообщения без ключа
+	done      chan kafka.Message
+	workers   sync.WaitGroup
+	committed chan struct{}
+}
+
+func newKeyedWorkers(workers int) *keyedWorkers {
+	p := &keyedWorkers{
+		queues:    make([]chan kafka.Message, workers),
+		done:      make(chan kafka.Message, workers),
+		committed: make(chan struct{}),
+	}
+	// Очереди без буфера: сообщение не ждет в очереди занятого обработчика
+	for i := range p.queues {
+		p.queues[i] = make(chan kafka.Message)
+	}
+	return p
+}
+
+// start запускает обработчики и commit, получающий успешно обработанные сообщения.
+// process возвращает false, если сообщение не обработано.
+func (p *keyedWorkers) start(process func(kafka.Message) bool, commit func(<-chan kafka.Message)) {
+	for _, queue := range p.queues {
+		p.workers.Add(1)
+		go func(queue <-chan kafka.Message) {
+			defer p.workers.Done()
+			for msg := range queue {
+				if process(msg) {
+					p.done <- msg
+				}
+			}
+		}(queue)
+	}
+	go func() {
+		commit(p.done)
+		close(p.committed)
+	}()
+}
+
+// dispatch передает сообщение обработчику, ожидая, пока тот освободится. false - если ctx отменен раньше.
+func (p *keyedWorkers) dispatch(ctx context.Context, msg kafka.Message) bool {
+	var index int
+	if key := messageKey(msg); key != "" {
+		index = workerIndex([]byte(key), len(p.queues))
+	} else {
+		index = p.next
+		p.next = (p.next + 1) % len(p.queues)
+	}
+
+	select {
+	case p.queues[index] <- msg:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// stop ждет завершения обработчиков и фиксации смещений.
+func (p *keyedWorkers) stop() {
+	for _, queue := range p.queues {
+		close(queue)
+	}
+	p.workers.Wait()
+	close(p.done)
+	<-p.committed
+}
+
+// workerIndex выбирает обработчик по ключу сообщения: сообщения с одинаковым ключом
+// всегда попадают к одному обработчику.
+func workerIndex(key []byte, workers int) int {
+	h := fnv.New32a()
+	_, _ = h.Write(key)
+	return int(h.Sum32() % uint32(workers))
+}
+
+// offsetTracker определяет смещения, которые можно зафиксировать: смещение раздела фиксируется,
+// только когда обработаны все прочитанные до него сообщения раздела.
+type offsetTracker struct {
+	mu         sync.Mutex
+	partitions map[topicPartition]*partitionProgress
+}
+
+type topicPartition struct {
+	topic     string
+	partition int
+}
+
+type partitionProgress struct {
+	pending   []int64        // Прочитанные и еще не зафиксированные смещения в порядке чтения
+	done      map[int64]bool // Обработанные смещения из pending
+	committed int64          // Последнее зафиксированное смещение, -1 - еще не было
+	commit    *kafka.Message // Сообщение, смещение которого нужно зафиксировать
+}
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{partitions: make(map[topicPartition]*partitionProgress)}
+}
+
+func (t *offsetTracker) progress(msg kafka.Message) *partitionProgress {
+	key := topicPartition{topic: msg.Topic, partition: msg.Partition}
+	p, ok := t.partitions[key]
+	if !ok {
+		p = &partitionProgress{done: make(map[int64]bool), committed: -1}
+		t.partitions[key] = p
+	}
+	return p
+}
+
+// fetched отмечает сообщение прочитанным. Вызывается в порядке чтения до передачи обработчику.
+func (t *offsetTracker) fetched(msg kafka.Message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p := t.progress(msg)
+	p.pending = append(p.pending, msg.Offset)
+}
+
+// completed отмечает сообщение обработанным.
+func (t *offsetTracker) completed(msg kafka.Message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p := t.progress(msg)
+	p.done[msg.Offset] = true
+
+	for len(p.pending) > 0 && p.done[p.pending[0]] {
+		offset := p.pending[0]
+		delete(p.done, offset)
+		p.pending = p.pending[1:]
+		// После перебалансировки раздел может быть прочитан повторно с меньшего смещения
+		if offset > p.committed {
+			p.committed = offset
+			p.commit = &kafka.Message{Topic: msg.Topic, Partition: msg.Partition, Offset: offset}
+		}
+	}
+}
+
+// commits возвращает по топикам сообщения, смещения которых нужно зафиксировать, и сбрасывает их.
+func (t *offsetTracker) commits() map[string][]kafka.Message {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	commits := make(map[string][]kafka.Message)
+	for key, p := range t.partitions {
+		if p.commit != nil {
+			commits[key.topic] = append(commits[key.topic], *p.commit)
+			p.commit = nil
+		}
+	}
+	return commits
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"ProjectMessageService/config"
+	"ProjectMessageService/internal/utils"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOffsetTracker(t *testing.T) {
+	tracker := newOffsetTracker()
+	msg := func(partition int, offset int64) kafka.Message {
+		return kafka.Message{Topic: "message-topic", Partition: partition, Offset: offset}
+	}
+	for offset := int64(10); offset < 13; offset++ {
+		tracker.fetched(msg(0, offset))
+	}
+	tracker.fetched(msg(1, 5))
+
+	// Пока не обработано сообщение 10, смещения раздела 0 не фиксируются
+	tracker.completed(msg(0, 12))
+	tracker.completed(msg(0, 11))
+	tracker.completed(msg(1, 5))
+	require.Equal(t, map[string][]kafka.Message{"message-topic": {msg(1, 5)}}, tracker.commits())
+
+	tracker.completed(msg(0, 10))
+	require.Equal(t, map[string][]kafka.Message{"message-topic": {msg(0, 12)}}, tracker.commits())
+	require.Empty(t, tracker.commits())
+
+	// Повторно прочитанное после перебалансировки сообщение не откатывает смещение
+	tracker.fetched(msg(0, 11))
+	tracker.completed(msg(0, 11))
+	require.Empty(t, tracker.commits())
+}
+
+func TestWorkerIndex(t *testing.T) {
+	require.Equal(t, workerIndex([]byte("user-42"), 8), workerIndex([]byte("user-42"), 8))
+	require.Zero(t, workerIndex([]byte("user-42"), 1))
+
+	seen := make(map[int]bool)
+	for _, key := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		index := workerIndex([]byte(key), 4)
+		require.True(t, index >= 0 && index < 4)
+		seen[index] = true
+	}
+	require.Greater(t, len(seen), 1)
+}
+
+// testMessages возвращает next, отдающий сообщения по порядку, и false после последнего.
+func testMessages(messages []kafka.Message) func(context.Context) (kafka.Message, bool) {
+	var i int
+	return func(ctx context.Context) (kafka.Message, bool) {
+		if i == len(messages) || ctx.Err() != nil {
+			return kafka.Message{}, false
+		}
+		i++
+		return messages[i-1], true
+	}
+}
+
+// testCommits собирает зафиксированные смещения по разделам.
+type testCommits struct {
+	mu      sync.Mutex
+	offsets map[int][]int64
+}
+
+func (c *testCommits) commit(commits map[string][]kafka.Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, messages := range commits {
+		for _, msg := range messages {
+			c.offsets[msg.Partition] = append(c.offsets[msg.Partition], msg.Offset)
+		}
+	}
+}
+
+func (c *testCommits) last(partition int) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	offsets := c.offsets[partition]
+	if len(offsets) == 0 {
+		return -1
+	}
+	return offsets[len(offsets)-1]
+}
+
+func TestKeyedWorkersOrderPerKey(t *testing.T) {
+	keys := []string{"user-1", "user-2", "user-3", "user-4"}
+	var messages []kafka.Message
+	for i := 0; i < 200; i++ {
+		messages = append(messages, kafka.Message{Topic: "message-topic", Key: []byte(keys[i%len(keys)]), Offset: int64(i)})
+	}
+
+	var mu sync.Mutex
+	processed := make(map[string][]int64)
+	commits := &testCommits{offsets: make(map[int][]int64)}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runKeyedWorkers(ctx, cancel, 4, testMessages(messages), func(msg kafka.Message) error {
+		time.Sleep(time.Duration(msg.Offset%3) * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		processed[string(msg.Key)] = append(processed[string(msg.Key)], msg.Offset)
+		return nil
+	}, commits.commit)
+
+	for _, key := range keys {
+		require.Len(t, processed[key], 50)
+		require.IsIncreasing(t, processed[key])
+	}
+	require.EqualValues(t, 199, commits.last(0))
+	require.IsIncreasing(t, commits.offsets[0])
+}
+
+func TestKeyedWorkersCommitAfterProcessing(t *testing.T) {
+	// Сообщения без ключа распределяются по очереди: 0 - первому обработчику, 1 - второму
+	messages := []kafka.Message{
+		{Topic: "message-topic", Key: []byte("message"), Offset: 0},
+		{Topic: "message-topic", Key: []byte("message"), Offset: 1},
+	}
+	release := make(chan struct{})
+	secondDone := make(chan struct{})
+	commits := &testCommits{offsets: make(map[int][]int64)}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	finished := make(chan struct{})
+	go func() {
+		runKeyedWorkers(ctx, cancel, 2, testMessages(messages), func(msg kafka.Message) error {
+			if msg.Offset == 0 {
+				<-release
+			} else {
+				close(secondDone)
+			}
+			return nil
+		}, commits.commit)
+		close(finished)
+	}()
+
+	// Сообщение 1 обработано, но смещение не фиксируется, пока обрабатывается сообщение 0
+	<-secondDone
+	time.Sleep(20 * time.Millisecond)
+	require.EqualValues(t, -1, commits.last(0))
+
+	close(release)
+	<-finished
+	require.EqualValues(t, 1, commits.last(0))
+}
+
+func TestKeyedWorkersStopOnError(t *testing.T) {
+	var messages []kafka.Message
+	for i := 0; i < 10; i++ {
+		messages = append(messages, kafka.Message{Topic: "message-topic", Offset: int64(i)})
+	}
+
+	var processed []int64
+	commits := &testCommits{offsets: make(map[int][]int64)}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runKeyedWorkers(ctx, cancel, 1, testMessages(messages), func(msg kafka.Message) error {
+		processed = append(processed, msg.Offset)
+		if msg.Offset == 3 {
+			return errors.New("processing failed")
+		}
+		return nil
+	}, commits.commit)
+
+	require.Error(t, ctx.Err())
+	require.Equal(t, []int64{0, 1, 2, 3}, processed)
+	require.EqualValues(t, 2, commits.last(0))
+}
+
+// fakeLevelReader отдает сообщения по порядку, затем ждет отмены. Как и kafka.Reader, после Close
+// не фиксирует смещения.
+type fakeLevelReader struct {
+	mu        sync.Mutex
+	messages  []kafka.Message
+	committed []kafka.Message
+	closed    bool
+}
+
+func (r *fakeLevelReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	r.mu.Lock()
+	if len(r.messages) > 0 {
+		msg := r.messages[0]
+		r.messages = r.messages[1:]
+		r.mu.Unlock()
+		return msg, nil
+	}
+	r.mu.Unlock()
+	<-ctx.Done()
+	return kafka.Message{}, ctx.Err()
+}
+
+func (r *fakeLevelReader) CommitMessages(_ context.Context, msgs ...kafka.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return io.ErrClosedPipe
+	}
+	r.committed = append(r.committed, msgs...)
+	return nil
+}
+
+func (r *fakeLevelReader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	return nil
+}
+
+func TestConsumeLevelsCommitsAfterStop(t *testing.T) {
+	topic, _ := priorityTopicAndGroup("message", utils.PriorityNormal)
+	msg := kafka.Message{Topic: topic, Offset: 7}
+	reader := &fakeLevelReader{messages: []kafka.Message{msg}}
+	levels := []*priorityLevel{{priority: utils.PriorityNormal, weight: 1, reader: reader}}
+	s := &MessageService{app: config.SetupApplication()}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	s.consumeLevels(ctx, "message", levels, func(kafka.Message) error {
+		// Обработка завершается уже после остановки консьюмера
+		close(started)
+		cancel()
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	})
+
+	<-started
+	require.True(t, reader.closed)
+	require.Equal(t, []kafka.Message{msg}, reader.committed)
+}
@@ -0,0 +1,44 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// QueryLogger создает спаны для запросов pgx. В pgx v4 нет хуков трассировки, поэтому спан
+// строится по записи журнала, которую pgx пишет после завершения запроса: время начала
+// вычисляется из длительности запроса.
+type QueryLogger struct{}
+
+var _ pgx.Logger = QueryLogger{}
+
+// Сообщения журнала pgx, соответствующие выполненным запросам.
+var tracedOperations = map[string]bool{"Query": true, "Exec": true, "SendBatch": true}
+
+func (QueryLogger) Log(ctx context.Context, _ pgx.LogLevel, msg string, data map[string]interface{}) {
+	if !tracedOperations[msg] || !trace.SpanFromContext(ctx).SpanContext().IsValid() {
+		return
+	}
+
+	end := time.Now()
+	duration, _ := data["time"].(time.Duration)
+	attrs := []attribute.KeyValue{semconv.DBSystemPostgreSQL, semconv.DBOperation(msg)}
+	if sql, ok := data["sql"].(string); ok {
+		attrs = append(attrs, semconv.DBStatement(sql))
+	}
+
+	_, span := Tracer().Start(ctx, "postgres."+msg,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(end.Add(-duration)),
+		trace.WithAttributes(attrs...),
+	)
+	if err, ok := data["err"].(error); ok {
+		RecordError(span, err)
+	}
+	span.End(trace.WithTimestamp(end))
+}
@@ -1,14 +1,201 @@
 package utils
 
-import `time`
+import (
+	"encoding/json"
+	"fmt"
+	`time`
+)
 
+// Message - сообщение топика. Тело задается либо текстом Message, либо JSON-документом Payload.
+// Key определяет раздел Kafka (по умолчанию используется имя топика), Headers передаются
+// в заголовки Kafka.
 type Message struct {
-	Topic   string `json:"topic" binding:"required,topic"`
-	Message string `json:"message" binding:"required"`
+	Topic       string            `json:"topic" binding:"required,topic"`
+	Message     string            `json:"message,omitempty" binding:"required_without=Payload,excluded_with=Payload"`
+	Payload     json.RawMessage   `json:"payload,omitempty"`
+	Key         string            `json:"key,omitempty" binding:"max=256"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	ContentType string            `json:"content_type,omitempty" binding:"max=128"`
+	// Отложенная доставка: время или задержка (15m, 2h30m), после которой сообщение будет опубликовано
+	DeliverAt *time.Time `json:"deliver_at,omitempty" binding:"excluded_with=Delay"`
+	Delay     string     `json:"delay,omitempty" binding:"excluded_with=DeliverAt"`
+	// Срок жизни: время или длительность от публикации (5m), после которой сообщение не обрабатывается.
+	// Без них действует TTL топика из MESSAGE_TTL
+	ExpiresAt *time.Time `json:"expires_at,omitempty" binding:"excluded_with=TTL"`
+	TTL       string     `json:"ttl,omitempty" binding:"excluded_with=ExpiresAt"`
+	// Приоритет: high, normal (по умолчанию) или low
+	Priority string `json:"priority,omitempty" binding:"omitempty,oneof=high normal low"`
 }
 
-type Messages struct {
-	Topic string `json:"topic" binding:"required"`
+// Уровни приоритета сообщений.
+const (
+	PriorityHigh   = "high"
+	PriorityNormal = "normal"
+	PriorityLow    = "low"
+)
+
+// Priorities - уровни приоритета в порядке обработки.
+var Priorities = []string{PriorityHigh, PriorityNormal, PriorityLow}
+
+// PriorityLevel возвращает приоритет сообщения с учетом значения по умолчанию.
+func (m Message) PriorityLevel() string {
+	if m.Priority == "" {
+		return PriorityNormal
+	}
+	return m.Priority
+}
+
+// MaxScheduleDelay - насколько далеко в будущее можно отложить доставку сообщения.
+const MaxScheduleDelay = 365 * 24 * time.Hour
+
+// ScheduledAt возвращает время отложенной доставки. Второе значение - false, если сообщение нужно
+// опубликовать сразу: время не задано или уже наступило.
+func (m Message) ScheduledAt(now time.Time) (time.Time, bool, error) {
+	deliverAt := now
+	switch {
+	case m.DeliverAt != nil:
+		deliverAt = *m.DeliverAt
+	case m.Delay != "":
+		delay, err := time.ParseDuration(m.Delay)
+		if err != nil {
+			return now, false, fmt.Errorf("invalid delay %q: %w", m.Delay, err)
+		}
+		if delay < 0 {
+			return now, false, fmt.Errorf("delay must not be negative")
+		}
+		deliverAt = now.Add(delay)
+	}
+
+	if deliverAt.Sub(now) > MaxScheduleDelay {
+		return now, false, fmt.Errorf("delivery can be scheduled at most %s ahead", MaxScheduleDelay)
+	}
+	return deliverAt, deliverAt.After(now), nil
+}
+
+// Immediate возвращает копию сообщения без параметров отложенной доставки.
+func (m Message) Immediate() Message {
+	m.DeliverAt = nil
+	m.Delay = ""
+	return m
+}
+
+// TTLDuration возвращает срок жизни из поля TTL или 0, если он не задан.
+func (m Message) TTLDuration() (time.Duration, error) {
+	if m.TTL == "" {
+		return 0, nil
+	}
+	ttl, err := time.ParseDuration(m.TTL)
+	if err != nil {
+		return 0, fmt.Errorf("invalid ttl %q: %w", m.TTL, err)
+	}
+	if ttl <= 0 {
+		return 0, fmt.Errorf("ttl must be positive")
+	}
+	return ttl, nil
+}
+
+// ValidateExpiry проверяет срок жизни публикуемого сообщения: TTL должен быть положительным,
+// а ExpiresAt - позже now и времени доставки deliverAt, иначе сообщение истекло бы до обработки.
+func (m Message) ValidateExpiry(now, deliverAt time.Time) error {
+	if _, err := m.TTLDuration(); err != nil {
+		return err
+	}
+	if m.ExpiresAt == nil {
+		return nil
+	}
+	if !m.ExpiresAt.After(now) {
+		return fmt.Errorf("expires_at must be in the future")
+	}
+	if !m.ExpiresAt.After(deliverAt) {
+		return fmt.Errorf("expires_at must be after deliver_at")
+	}
+	return nil
+}
+
+// WithExpiry возвращает копию сообщения, у которой ExpiresAt вычислено из TTL сообщения или,
+// если он не задан, из topicTTL (0 - без ограничения). Заданное ExpiresAt не меняется.
+func (m Message) WithExpiry(now time.Time, topicTTL time.Duration) Message {
+	if m.ExpiresAt != nil {
+		return m
+	}
+	if ttl, err := m.TTLDuration(); err == nil && ttl > 0 {
+		topicTTL = ttl
+	}
+	if topicTTL > 0 {
+		expiresAt := now.Add(topicTTL)
+		m.ExpiresAt = &expiresAt
+	}
+	return m
+}
+
+// Expired сообщает, истек ли срок жизни сообщения к моменту now.
+func (m Message) Expired(now time.Time) bool {
+	return m.ExpiresAt != nil && !now.Before(*m.ExpiresAt)
+}
+
+// Content возвращает тело сообщения в том виде, в котором оно хранится в колонке content
+// и передается в Kafka.
+func (m Message) Content() string {
+	if len(m.Payload) > 0 {
+		return string(m.Payload)
+	}
+	return m.Message
+}
+
+// RoutedFromHeader - заголовок копии сообщения, созданной правилом маршрутизации: топик и id исходного сообщения.
+const RoutedFromHeader = "x-routed-from"
+
+// RoutedFrom возвращает исходное сообщение копии маршрутизации (топик/id) или пустую строку.
+// Копии разных сообщений с одинаковым содержимым различаются по нему при дедупликации.
+func (m Message) RoutedFrom() string {
+	return m.Headers[RoutedFromHeader]
+}
+
+// PartitionKey возвращает ключ сообщения для Kafka.
+func (m Message) PartitionKey() string {
+	if m.Key != "" {
+		return m.Key
+	}
+	return m.Topic
+}
+
+// GetContentType возвращает тип содержимого с учетом значения по умолчанию.
+func (m Message) GetContentType() string {
+	switch {
+	case m.ContentType != "":
+		return m.ContentType
+	case len(m.Payload) > 0:
+		return ContentTypeJSON
+	default:
+		return ContentTypeText
+	}
+}
+
+const (
+	ContentTypeJSON = "application/json"
+	ContentTypeText = "text/plain"
+)
+
+// MessageBatch - пакет сообщений, не более 1000 за запрос.
+type MessageBatch struct {
+	Messages []Message `json:"messages" binding:"required,min=1,max=1000"`
+}
+
+// Статусы элементов пакетной публикации.
+const (
+	BatchStatusAccepted  = "accepted"
+	BatchStatusScheduled = "scheduled"
+	BatchStatusDuplicate = "duplicate"
+	BatchStatusInvalid   = "invalid"
+	BatchStatusFailed    = "failed"
+)
+
+type MessageResult struct {
+	Index  int    `json:"index"`
+	Topic  string `json:"topic"`
+	ID     int    `json:"id,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
 }
 
 func TimeConnect(fn func() error, attempts int, delay time.Duration) (err error) {
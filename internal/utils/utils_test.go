@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageScheduledAt(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	at, scheduled, err := Message{}.ScheduledAt(now)
+	require.NoError(t, err)
+	require.False(t, scheduled)
+	require.Equal(t, now, at)
+
+	at, scheduled, err = Message{Delay: "15m"}.ScheduledAt(now)
+	require.NoError(t, err)
+	require.True(t, scheduled)
+	require.Equal(t, now.Add(15*time.Minute), at)
+
+	// Наступившее время доставки означает публикацию сразу
+	past := now.Add(-time.Minute)
+	_, scheduled, err = Message{DeliverAt: &past}.ScheduledAt(now)
+	require.NoError(t, err)
+	require.False(t, scheduled)
+
+	future := now.Add(time.Hour)
+	at, scheduled, err = Message{DeliverAt: &future, Delay: "1m"}.ScheduledAt(now)
+	require.NoError(t, err)
+	require.True(t, scheduled)
+	require.Equal(t, future, at)
+
+	for _, delay := range []string{"soon", "-1m"} {
+		_, _, err = Message{Delay: delay}.ScheduledAt(now)
+		require.Error(t, err, delay)
+	}
+	tooFar := now.Add(MaxScheduleDelay + time.Second)
+	_, _, err = Message{DeliverAt: &tooFar}.ScheduledAt(now)
+	require.Error(t, err)
+
+	immediate := Message{DeliverAt: &future, Delay: "1m", Message: "hi"}.Immediate()
+	require.Nil(t, immediate.DeliverAt)
+	require.Empty(t, immediate.Delay)
+	require.Equal(t, "hi", immediate.Message)
+}
+
+func TestMessageValidateExpiry(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Minute)
+	soon := now.Add(time.Minute)
+
+	require.NoError(t, Message{}.ValidateExpiry(now, now))
+	require.NoError(t, Message{TTL: "5m"}.ValidateExpiry(now, now))
+	require.NoError(t, Message{ExpiresAt: &soon}.ValidateExpiry(now, now))
+
+	require.Error(t, Message{TTL: "-5m"}.ValidateExpiry(now, now))
+	require.Error(t, Message{ExpiresAt: &past}.ValidateExpiry(now, now))
+	require.Error(t, Message{ExpiresAt: &now}.ValidateExpiry(now, now))
+	// Сообщение не должно истечь до отложенной доставки
+	require.Error(t, Message{ExpiresAt: &soon}.ValidateExpiry(now, now.Add(time.Hour)))
+}
@@ -8,7 +8,7 @@ import (
 )
 
 func IsSupportedCurrency(msg string) bool {
-	for _, currencyCode := range config.MessageTypes {
+	for _, currencyCode := range config.MessageTypes() {
 		if currencyCode == msg {
 			return true
 		}
@@ -3,4 +3,11 @@ package util
 const (
 	DepositorRole = "depositor"
 	BankerRole    = "banker"
+	AdminRole     = "admin"
+	// PublisherRole получают внутренние публикаторы, аутентифицированные клиентским сертификатом
+	PublisherRole = "publisher"
 )
+
+// CertOwnerPrefix отмечает владельцев, аутентифицированных клиентским сертификатом: cert:<CommonName>.
+// Имена пользователей с этим префиксом не регистрируются, чтобы CommonName не совпал с пользователем.
+const CertOwnerPrefix = "cert:"